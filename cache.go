@@ -0,0 +1,240 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cacheDirectoryName contains the name of the directory in the user's cache directory
+// (e.g. $XDG_CACHE_HOME, ~/.cache) the remote IPs are cached in.
+const cacheDirectoryName = "myip"
+
+// ipCache contains the settings of the on-disk cache for remote IPs.
+type ipCache struct {
+	// directory is the directory the cache files are written to.
+	directory string
+
+	// ttl is the duration the cached IPs are returned for.
+	// The cache is disabled if the ttl is zero.
+	ttl time.Duration
+
+	// refresh forces a new lookup and updates the cache.
+	refresh bool
+
+	// errors receives the errors of cache files that cannot be written (optional).
+	errors io.Writer
+}
+
+// cacheEntry contains the cached IPs of one IP family and provider set.
+type cacheEntry struct {
+	Key  string    `json:"key"`
+	Time time.Time `json:"time"`
+	IPs  []string  `json:"ips"`
+}
+
+// getIPCache returns the cache settings for the given ttl and refresh flag.
+// The cache files are written to the "myip" directory in the user's cache directory
+// and errors writing them are reported on stderr.
+func getIPCache(ttl time.Duration, refresh bool) (ipCache, error) {
+
+	if ttl < 0 {
+		return ipCache{}, fmt.Errorf("The cache duration (%s) must not be negative", ttl)
+	}
+
+	if refresh && ttl == 0 {
+		return ipCache{}, fmt.Errorf("The cache can only be refreshed if a cache duration is given (-cache)")
+	}
+
+	if ttl == 0 {
+		return ipCache{}, nil
+	}
+
+	userCacheDirectory, cacheDirectoryError := os.UserCacheDir()
+	if cacheDirectoryError != nil {
+		return ipCache{}, fmt.Errorf("Unable to determine the cache directory: %s", cacheDirectoryError.Error())
+	}
+
+	return ipCache{
+		directory: filepath.Join(userCacheDirectory, cacheDirectoryName),
+		ttl:       ttl,
+		refresh:   refresh,
+		errors:    os.Stderr,
+	}, nil
+}
+
+// getRemoteIPCacheKey returns the cache key of the provider set of the given options:
+// the methods, the strategy, the proxies and the source interface and address.
+func getRemoteIPCacheKey(options myip.RemoteIPProviderOptions) string {
+
+	methods := options.Methods
+	if len(methods) == 0 {
+		methods = []myip.RemoteMethod{myip.RemoteMethodHTTP}
+	}
+
+	var methodNames []string
+	for _, method := range methods {
+		methodNames = append(methodNames, method.String())
+	}
+
+	var proxies []string
+	for _, proxyURL := range []*url.URL{options.Proxy, options.IPv4Proxy, options.IPv6Proxy} {
+		if proxyURL == nil {
+			proxies = append(proxies, "")
+			continue
+		}
+
+		proxies = append(proxies, proxyURL.String())
+	}
+
+	var sourceAddress string
+	if options.SourceAddress != nil {
+		sourceAddress = options.SourceAddress.String()
+	}
+
+	return fmt.Sprintf("remote method=%s strategy=%s proxy=%s source-interface=%s source-address=%s",
+		strings.Join(methodNames, ","), options.Strategy, strings.Join(proxies, ","), options.SourceInterface, sourceAddress)
+}
+
+// cachedIPAddresser returns the IPs of an IP provider from the on-disk cache
+// as long as they are fresh and asks the IP provider (and updates the cache) otherwise.
+type cachedIPAddresser struct {
+	ipProvider ipAddresser
+	cache      ipCache
+	key        string
+}
+
+// newCachedIPAddresser returns the given IP provider with the given cache for the given provider set key.
+// The IP provider is returned unchanged if the cache is disabled.
+func newCachedIPAddresser(ipProvider ipAddresser, cache ipCache, key string) ipAddresser {
+	if cache.ttl <= 0 {
+		return ipProvider
+	}
+
+	return cachedIPAddresser{ipProvider, cache, key}
+}
+
+// GetIPv4Addresses returns the cached IPv4 addresses or the IPv4 addresses of the IP provider.
+func (p cachedIPAddresser) GetIPv4Addresses() ([]net.IP, error) {
+	return p.getIPs("ipv4", p.ipProvider.GetIPv4Addresses)
+}
+
+// GetIPv6Addresses returns the cached IPv6 addresses or the IPv6 addresses of the IP provider.
+func (p cachedIPAddresser) GetIPv6Addresses() ([]net.IP, error) {
+	return p.getIPs("ipv6", p.ipProvider.GetIPv6Addresses)
+}
+
+// getIPs returns the cached IPs of the given IP family if they are fresh.
+// Otherwise the IPs are requested with the given function and written to the cache.
+// Errors are not cached and a cache that cannot be written does not fail the lookup;
+// the error is reported to the error writer of the cache instead.
+func (p cachedIPAddresser) getIPs(ipFamily string, getIPs func() ([]net.IP, error)) ([]net.IP, error) {
+
+	key := ipFamily + " " + p.key
+	cachePath := p.getCachePath(key)
+
+	if !p.cache.refresh {
+		if ips, ok := readCacheEntry(cachePath, key, p.cache.ttl, time.Now()); ok {
+			return ips, nil
+		}
+	}
+
+	ips, err := getIPs()
+	if err != nil || len(ips) == 0 {
+		return ips, err
+	}
+
+	if err := writeCacheEntry(cachePath, key, ips, time.Now()); err != nil && p.cache.errors != nil {
+		fmt.Fprintf(p.cache.errors, "Unable to cache the %s IPs: %s\n", ipFamily, err.Error())
+	}
+
+	return ips, nil
+}
+
+// getCachePath returns the path of the cache file for the given key.
+func (p cachedIPAddresser) getCachePath(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(p.cache.directory, fmt.Sprintf("%s-%s.json", strings.Fields(key)[0], hex.EncodeToString(hash[:8])))
+}
+
+// readCacheEntry returns the IPs of the cache file with the given path
+// if the file exists, belongs to the given key and is younger than the given ttl.
+func readCacheEntry(cachePath, key string, ttl time.Duration, now time.Time) ([]net.IP, bool) {
+
+	content, readError := os.ReadFile(cachePath)
+	if readError != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+
+	age := now.Sub(entry.Time)
+	if age < 0 || age >= ttl || len(entry.IPs) == 0 {
+		return nil, false
+	}
+
+	var ips []net.IP
+	for _, ipString := range entry.IPs {
+		ip := net.ParseIP(ipString)
+		if ip == nil {
+			return nil, false
+		}
+
+		ips = append(ips, ip)
+	}
+
+	return ips, true
+}
+
+// writeCacheEntry writes the given IPs for the given key to the cache file with the given path.
+// The file is replaced atomically so concurrent calls never read a partial entry.
+func writeCacheEntry(cachePath, key string, ips []net.IP, now time.Time) error {
+
+	entry := cacheEntry{Key: key, Time: now}
+	for _, ip := range ips {
+		entry.IPs = append(entry.IPs, ip.String())
+	}
+
+	content, marshalError := json.Marshal(entry)
+	if marshalError != nil {
+		return marshalError
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+		return fmt.Errorf("Unable to create the cache directory: %s", err.Error())
+	}
+
+	file, createError := os.CreateTemp(filepath.Dir(cachePath), filepath.Base(cachePath)+".*")
+	if createError != nil {
+		return fmt.Errorf("Unable to create the cache file: %s", createError.Error())
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("Unable to write the cache file: %s", err.Error())
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Unable to write the cache file: %s", err.Error())
+	}
+
+	return os.Rename(file.Name(), cachePath)
+}
@@ -0,0 +1,193 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"github.com/andreaskoch/myip/myiptest"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A fresh cache entry should be returned without asking the IP provider.
+func Test_cachedIPAddresser_FreshEntry_ProviderIsAskedOnce(t *testing.T) {
+	// arrange
+	ipProvider := myiptest.NewIPAddresser(myiptest.Result{IPs: myiptest.IPs("203.0.113.5")}, myiptest.Result{})
+	cache := ipCache{directory: t.TempDir(), ttl: time.Minute}
+	cachedIPProvider := newCachedIPAddresser(ipProvider, cache, "remote")
+
+	// act
+	for call := 0; call < 3; call++ {
+		ips, err := cachedIPProvider.GetIPv4Addresses()
+
+		// assert
+		if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("203.0.113.5")) {
+			t.Errorf("GetIPv4Addresses returned %v (%v) but should have returned 203.0.113.5", ips, err)
+		}
+	}
+
+	if ipProvider.IPv4Calls() != 1 {
+		t.Errorf("The IP provider was asked %d times but should have been asked once", ipProvider.IPv4Calls())
+	}
+}
+
+// A refresh should ask the IP provider and update the cache.
+func Test_cachedIPAddresser_Refresh_ProviderIsAskedAndCacheIsUpdated(t *testing.T) {
+	// arrange
+	directory := t.TempDir()
+	oldIPProvider := myiptest.NewIPAddresser(myiptest.Result{IPs: myiptest.IPs("203.0.113.5")}, myiptest.Result{})
+	newIPProvider := myiptest.NewIPAddresser(myiptest.Result{IPs: myiptest.IPs("198.51.100.7")}, myiptest.Result{})
+
+	newCachedIPAddresser(oldIPProvider, ipCache{directory: directory, ttl: time.Minute}, "remote").GetIPv4Addresses()
+
+	// act
+	newCachedIPAddresser(newIPProvider, ipCache{directory: directory, ttl: time.Minute, refresh: true}, "remote").GetIPv4Addresses()
+	ips, err := newCachedIPAddresser(oldIPProvider, ipCache{directory: directory, ttl: time.Minute}, "remote").GetIPv4Addresses()
+
+	// assert
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("198.51.100.7")) {
+		t.Errorf("GetIPv4Addresses returned %v (%v) but should have returned the refreshed IP 198.51.100.7", ips, err)
+	}
+
+	if newIPProvider.IPv4Calls() != 1 || oldIPProvider.IPv4Calls() != 1 {
+		t.Errorf("The IP providers were asked %d and %d times but should have been asked once each", oldIPProvider.IPv4Calls(), newIPProvider.IPv4Calls())
+	}
+}
+
+// Errors should not be cached and the entries of other IP families and provider sets should not be returned.
+func Test_cachedIPAddresser_ErrorsAndOtherKeys_ProviderIsAsked(t *testing.T) {
+	// arrange
+	directory := t.TempDir()
+	failingIPProvider := myiptest.NewIPAddresser(myiptest.Result{Err: fmt.Errorf("Timeout")}, myiptest.Result{IPs: myiptest.IPs("2001:db8::1")})
+	ipProvider := myiptest.NewIPAddresser(myiptest.Result{IPs: myiptest.IPs("203.0.113.5")}, myiptest.Result{IPs: myiptest.IPs("2001:db8::1")})
+	cache := ipCache{directory: directory, ttl: time.Minute}
+
+	newCachedIPAddresser(failingIPProvider, cache, "remote method=http").GetIPv4Addresses()
+	newCachedIPAddresser(failingIPProvider, cache, "remote method=http").GetIPv6Addresses()
+	newCachedIPAddresser(ipProvider, cache, "remote method=dns").GetIPv4Addresses()
+
+	// act
+	ips, err := newCachedIPAddresser(ipProvider, cache, "remote method=http").GetIPv4Addresses()
+
+	// assert
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("203.0.113.5")) {
+		t.Errorf("GetIPv4Addresses returned %v (%v) but should have returned 203.0.113.5", ips, err)
+	}
+
+	if ipProvider.IPv4Calls() != 2 {
+		t.Errorf("The IP provider was asked %d times but should have been asked twice", ipProvider.IPv4Calls())
+	}
+}
+
+// Only entries younger than the ttl should be returned.
+func Test_readCacheEntry_TTL_OnlyFreshEntriesAreReturned(t *testing.T) {
+	// arrange
+	cachePath := t.TempDir() + "/ipv4.json"
+	now := time.Now()
+	if err := writeCacheEntry(cachePath, "ipv4 remote", myiptest.IPs("203.0.113.5"), now.Add(-5*time.Minute)); err != nil {
+		t.Fatalf("writeCacheEntry returned an error: %s", err)
+	}
+
+	inputs := map[time.Duration]bool{
+		time.Minute:      false,
+		5 * time.Minute:  false,
+		10 * time.Minute: true,
+	}
+
+	for ttl, expectedResult := range inputs {
+
+		// act
+		_, ok := readCacheEntry(cachePath, "ipv4 remote", ttl, now)
+
+		// assert
+		if ok != expectedResult {
+			t.Errorf("readCacheEntry(ttl: %s) returned %t but should have returned %t", ttl, ok, expectedResult)
+		}
+	}
+}
+
+// Different provider sets should have different cache keys.
+func Test_getRemoteIPCacheKey_DifferentOptions_DifferentKeys(t *testing.T) {
+	// arrange
+	inputs := []myip.RemoteIPProviderOptions{
+		{},
+		{Methods: []myip.RemoteMethod{myip.RemoteMethodDNS}},
+		{Strategy: myip.StrategyFallback},
+		{SourceInterface: "wg0"},
+		{SourceAddress: net.ParseIP("10.8.0.2")},
+	}
+
+	keys := make(map[string]bool)
+	for _, input := range inputs {
+
+		// act
+		key := getRemoteIPCacheKey(input)
+
+		// assert
+		if keys[key] {
+			t.Errorf("getRemoteIPCacheKey(%+v) returned the duplicate key %q", input, key)
+		}
+
+		keys[key] = true
+	}
+
+	if getRemoteIPCacheKey(myip.RemoteIPProviderOptions{Retries: 3}) != getRemoteIPCacheKey(myip.RemoteIPProviderOptions{}) {
+		t.Errorf("getRemoteIPCacheKey should ignore the number of retries")
+	}
+}
+
+// A cache that cannot be written should not fail the lookup but the error should be reported.
+func Test_cachedIPAddresser_CacheNotWritable_ErrorIsReported(t *testing.T) {
+	// arrange
+	directory := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(directory, nil, 0600); err != nil {
+		t.Fatalf("Unable to create the file: %s", err.Error())
+	}
+
+	var errors bytes.Buffer
+	ipProvider := myiptest.NewIPAddresser(myiptest.Result{IPs: myiptest.IPs("203.0.113.5")}, myiptest.Result{})
+	cachedIPProvider := newCachedIPAddresser(ipProvider, ipCache{directory: directory, ttl: time.Minute, errors: &errors}, "remote")
+
+	// act
+	ips, err := cachedIPProvider.GetIPv4Addresses()
+
+	// assert
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("203.0.113.5")) {
+		t.Errorf("GetIPv4Addresses returned %v (%v) but should have returned 203.0.113.5", ips, err)
+	}
+
+	if !strings.HasPrefix(errors.String(), "Unable to cache the ipv4 IPs: ") {
+		t.Errorf("The cache reported %q but should have reported that the ipv4 IPs cannot be cached", errors.String())
+	}
+}
+
+// getIPCache should return an error for a negative duration and for a refresh without a cache duration.
+func Test_getIPCache_InvalidOptions_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		ttl     time.Duration
+		refresh bool
+	}{
+		{-time.Minute, false},
+		{0, true},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		_, err := getIPCache(input.ttl, input.refresh)
+
+		// assert
+		if err == nil {
+			t.Errorf("getIPCache(%s, %t) should return an error", input.ttl, input.refresh)
+		}
+	}
+}
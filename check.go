@@ -0,0 +1,127 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"net"
+	"strings"
+)
+
+// checkPredicates contains the conditions the addresses must meet for the "check" action.
+type checkPredicates struct {
+	// equals requires one of the addresses to be the given IP (nil: no condition)
+	equals net.IP
+
+	// hasGlobalIPv6 requires one of the addresses to be a globally routable IPv6 address
+	hasGlobalIPv6 bool
+
+	// matchesLocal requires one of the addresses to be assigned to a local interface
+	matchesLocal bool
+}
+
+// getCheckPredicates returns the check predicates for the given options.
+func getCheckPredicates(equalsOption string, hasGlobalIPv6, matchesLocal bool) (checkPredicates, error) {
+
+	predicates := checkPredicates{
+		hasGlobalIPv6: hasGlobalIPv6,
+		matchesLocal:  matchesLocal,
+	}
+
+	if equalsOption != "" {
+		predicates.equals = net.ParseIP(equalsOption)
+		if predicates.equals == nil {
+			return checkPredicates{}, fmt.Errorf("%q is not a valid IP address", equalsOption)
+		}
+	}
+
+	return predicates, nil
+}
+
+// checkOptions contains the settings the local or remote addresses are determined with for the "check" action.
+type checkOptions struct {
+	// selection selects the checked addresses (e.g. "all", "first")
+	selection string
+
+	// filter restricts the checked addresses to networks (-in, -not-in)
+	filter myip.NetworkFilter
+
+	// localIPOptions are used for the local addresses; including the network filter
+	localIPOptions myip.LocalIPProviderOptions
+
+	// snapshotPath is the path of a snapshot the local addresses are read from ("": the network interfaces)
+	snapshotPath string
+
+	// remoteIPOptions are used for the remote addresses
+	remoteIPOptions myip.RemoteIPProviderOptions
+
+	// cache contains the cache settings for the remote addresses
+	cache ipCache
+}
+
+// myCheck returns an error describing the reason if the current local or remote
+// IPv6 (or IPv4) addresses determined with the given options do not meet the given predicates.
+func myCheck(sourceName string, useIPv4 bool, options checkOptions, predicates checkPredicates) error {
+
+	var ips []net.IP
+	var ipError error
+	switch sourceName {
+	case actionnamelocal:
+		localIPs, localIPError := myLocalIP(options.selection, useIPv4, options.localIPOptions, options.snapshotPath)
+		ips, ipError = getIPs(localIPs), localIPError
+
+	case actionnameremote:
+		ips, ipError = myRemoteIP(options.selection, useIPv4, options.remoteIPOptions, options.filter, options.cache)
+
+	default:
+		return fmt.Errorf("The %q action can only check %q or %q addresses (not %q).", actionnamecheck, actionnamelocal, actionnameremote, sourceName)
+	}
+
+	if ipError != nil {
+		if options.filter.String() != "" {
+			return fmt.Errorf("%s (IPs %s).", strings.TrimSuffix(strings.TrimSpace(ipError.Error()), "."), options.filter.String())
+		}
+
+		return ipError
+	}
+
+	// the local addresses are only needed for comparing them with the checked addresses;
+	// they are determined with the same options as the checked local addresses
+	var localIPs []net.IP
+	if predicates.matchesLocal {
+		allLocalIPs, localIPError := myLocalIP(myip.SelectAll, useIPv4, options.localIPOptions, options.snapshotPath)
+		if localIPError != nil {
+			return localIPError
+		}
+
+		localIPs = getIPs(allLocalIPs)
+	}
+
+	return checkIPs(ips, predicates, localIPs)
+}
+
+// checkIPs returns an error describing the first predicate the given IPs do not meet.
+// The given local IPs are used for the matchesLocal predicate.
+func checkIPs(ips []net.IP, predicates checkPredicates, localIPs []net.IP) error {
+
+	if len(ips) == 0 {
+		return fmt.Errorf("No IPs available.")
+	}
+
+	if predicates.equals != nil && !containsIP(ips, predicates.equals) {
+		return fmt.Errorf("None of the IPs (%s) is %s.", formatInfoIPs(ips, nil), predicates.equals)
+	}
+
+	if predicates.hasGlobalIPv6 && !hasGlobalIPv6(ips) {
+		return fmt.Errorf("None of the IPs (%s) is a globally routable IPv6 address.", formatInfoIPs(ips, nil))
+	}
+
+	if predicates.matchesLocal && isBehindNAT(ips, localIPs) {
+		return fmt.Errorf("None of the IPs (%s) is assigned to a local interface.", formatInfoIPs(ips, nil))
+	}
+
+	return nil
+}
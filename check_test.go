@@ -0,0 +1,124 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"github.com/andreaskoch/myip"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// checkIPs should pass if all predicates are met.
+func Test_checkIPs_PredicatesAreMet_NoErrorIsReturned(t *testing.T) {
+	// arrange
+	ips := []net.IP{net.ParseIP("2001:4860::10"), net.ParseIP("fd00::10")}
+	localIPs := []net.IP{net.ParseIP("fd00::10")}
+	predicates := checkPredicates{
+		equals:        net.ParseIP("2001:4860::10"),
+		hasGlobalIPv6: true,
+		matchesLocal:  true,
+	}
+
+	// act
+	err := checkIPs(ips, predicates, localIPs)
+
+	// assert
+	if err != nil {
+		t.Errorf("checkIPs(%q, %v, %q) returned an error but should have passed: %s", ips, predicates, localIPs, err.Error())
+	}
+}
+
+// checkIPs should return an error that names the failed predicate.
+func Test_checkIPs_PredicateIsNotMet_ReasonIsReturned(t *testing.T) {
+	// arrange
+	ips := []net.IP{net.ParseIP("203.0.113.7")}
+	inputs := map[string]checkPredicates{
+		"is 203.0.113.5":                      {equals: net.ParseIP("203.0.113.5")},
+		"is a globally routable IPv6":         {hasGlobalIPv6: true},
+		"is assigned to a local interface":    {matchesLocal: true},
+		"None of the IPs (203.0.113.7) is 20": {equals: net.ParseIP("203.0.113.5"), matchesLocal: true},
+	}
+
+	for expectedReason, predicates := range inputs {
+
+		// act
+		err := checkIPs(ips, predicates, []net.IP{net.ParseIP("192.168.1.10")})
+
+		// assert
+		if err == nil {
+			t.Errorf("checkIPs(%q, %v) should return an error", ips, predicates)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), expectedReason) {
+			t.Errorf("checkIPs(%q, %v) returned %q which does not contain the reason %q", ips, predicates, err.Error(), expectedReason)
+		}
+	}
+}
+
+// getCheckPredicates should return an error if the expected IP is invalid.
+func Test_getCheckPredicates_InvalidEqualsOption_ErrorIsReturned(t *testing.T) {
+	// arrange
+	equalsOption := "203.0.113"
+
+	// act
+	_, err := getCheckPredicates(equalsOption, false, false)
+
+	// assert
+	if err == nil {
+		t.Errorf("getCheckPredicates(%q) should return an error because the IP is invalid", equalsOption)
+	}
+}
+
+// The local addresses of -matches-local should be determined with the same options
+// (snapshot, network filter) as the checked local addresses.
+func Test_myCheck_MatchesLocal_SnapshotAndFilterAreUsed(t *testing.T) {
+	// arrange
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := myip.Snapshot{
+		Interfaces: []myip.SnapshotInterface{
+			{Name: "eth0", Addresses: []string{"192.168.1.10/24"}},
+			{Name: "tun0", Addresses: []string{"10.8.0.2/24"}},
+		},
+	}
+
+	var buffer bytes.Buffer
+	if err := snapshot.Write(&buffer); err != nil {
+		t.Fatalf("Writing the snapshot failed: %s", err)
+	}
+
+	if err := os.WriteFile(snapshotPath, buffer.Bytes(), 0600); err != nil {
+		t.Fatalf("Writing the snapshot file failed: %s", err)
+	}
+
+	filter, _ := myip.ParseNetworkFilter("10.0.0.0/8", "")
+	options := checkOptions{
+		selection:      myip.SelectAll,
+		filter:         filter,
+		localIPOptions: myip.LocalIPProviderOptions{Networks: filter.Networks},
+		snapshotPath:   snapshotPath,
+	}
+
+	inputs := map[string]bool{
+		"10.8.0.2":     true,
+		"192.168.1.10": false,
+	}
+
+	for equals, expectedResult := range inputs {
+		predicates := checkPredicates{equals: net.ParseIP(equals), matchesLocal: true}
+
+		// act
+		err := myCheck(actionnamelocal, true, options, predicates)
+
+		// assert
+		if (err == nil) != expectedResult {
+			t.Errorf("myCheck(local, equals %s) returned %v but should have passed: %t", equals, err, expectedResult)
+		}
+	}
+}
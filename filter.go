@@ -0,0 +1,37 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/andreaskoch/myip"
+	"net"
+)
+
+// filteredIPAddresser returns the IPs of an IP provider that pass a network filter
+// (-in, -not-in).
+type filteredIPAddresser struct {
+	ipProvider ipAddresser
+	filter     myip.NetworkFilter
+}
+
+// GetIPv4Addresses returns the IPv4 addresses of the IP provider that pass the filter.
+func (p filteredIPAddresser) GetIPv4Addresses() ([]net.IP, error) {
+	ips, err := p.ipProvider.GetIPv4Addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.filter.FilterIPs(ips), nil
+}
+
+// GetIPv6Addresses returns the IPv6 addresses of the IP provider that pass the filter.
+func (p filteredIPAddresser) GetIPv6Addresses() ([]net.IP, error) {
+	ips, err := p.ipProvider.GetIPv6Addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.filter.FilterIPs(ips), nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/andreaskoch/myip"
+	"net"
+	"testing"
+)
+
+// The network filter should be applied before the selection so the
+// selection index refers to the filtered list of IPs.
+func Test_getMyIP_NetworkFilter_SelectionIsAppliedToFilteredIPs(t *testing.T) {
+	// arrange
+	ipProvider := testIPProvider{
+		ipv4IPs: []net.IP{
+			net.ParseIP("172.17.0.1"),
+			net.ParseIP("192.168.1.10"),
+			net.ParseIP("10.1.2.3"),
+			net.ParseIP("10.200.0.1"),
+		},
+	}
+	filter, _ := myip.ParseNetworkFilter("10.0.0.0/8,172.16.0.0/12", "172.17.0.0/16,10.200.0.0/16")
+
+	// act
+	ips, err := getMyIP(filteredIPAddresser{ipProvider, filter}, "first", true)
+
+	// assert
+	if err != nil {
+		t.Fatalf("getMyIP returned an error: %s", err.Error())
+	}
+
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.1.2.3")) {
+		t.Errorf("getMyIP returned %q but should have returned %q", ips, "10.1.2.3")
+	}
+}
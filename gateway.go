@@ -0,0 +1,77 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/andreaskoch/myip"
+)
+
+// The gatewayAddresser interface provides functions for
+// retrieving the default IPv4 and IPv6 gateways.
+type gatewayAddresser interface {
+	GetIPv4Gateways() ([]myip.Gateway, error)
+	GetIPv6Gateways() ([]myip.Gateway, error)
+}
+
+// myGateway returns the current default IPv6 (or IPv4) gateways.
+func myGateway(selectionOption string, useIPv4 bool) ([]myip.Gateway, error) {
+
+	gatewayProvider := myip.NewGatewayProvider()
+
+	return getMyGateways(gatewayProvider, selectionOption, useIPv4)
+}
+
+// getMyGateways returns the selected default IPv6 or IPv4 gateways from the given gateway provider.
+func getMyGateways(gatewayProvider gatewayAddresser, selectionOption string, useIPv4 bool) ([]myip.Gateway, error) {
+
+	// IPv6 vs IPv4
+	var allGateways []myip.Gateway
+	var gatewayErr error
+	if useIPv4 {
+		allGateways, gatewayErr = gatewayProvider.GetIPv4Gateways()
+	} else {
+		allGateways, gatewayErr = gatewayProvider.GetIPv6Gateways()
+	}
+
+	// handle errors
+	if gatewayErr != nil {
+		return nil, fmt.Errorf("%s\n", gatewayErr.Error())
+	}
+
+	// abort if no gateways are returned
+	if len(allGateways) == 0 {
+		return []myip.Gateway{}, fmt.Errorf("No %s gateways available.", getIPType(useIPv4))
+	}
+
+	// select one or more gateways
+	var keys []string
+	for _, gateway := range allGateways {
+		keys = append(keys, formatGateway(gateway))
+	}
+
+	selection, selectionError := myip.ParseSelection(selectionOption)
+	if selectionError != nil {
+		return nil, fmt.Errorf("%s\n", selectionError.Error())
+	}
+
+	selectedIndexes, selectionError := selection.Indexes(keys)
+	if selectionError != nil {
+		return nil, fmt.Errorf("%s\n", selectionError.Error())
+	}
+
+	var selectedGateways []myip.Gateway
+	for _, index := range selectedIndexes {
+		selectedGateways = append(selectedGateways, allGateways[index])
+	}
+
+	return selectedGateways, nil
+}
+
+// formatGateway returns the output line for the given gateway
+// (e.g. "192.168.1.1 dev eth0 metric 100").
+func formatGateway(gateway myip.Gateway) string {
+	return fmt.Sprintf("%s dev %s metric %d", gateway.IP, gateway.Interface, gateway.Metric)
+}
@@ -0,0 +1,65 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/andreaskoch/myip"
+	"net"
+	"testing"
+)
+
+// testGatewayProvider returns a fixed list of gateways.
+type testGatewayProvider struct {
+	ipv4Gateways []myip.Gateway
+	ipv6Gateways []myip.Gateway
+}
+
+func (p testGatewayProvider) GetIPv4Gateways() ([]myip.Gateway, error) {
+	return p.ipv4Gateways, nil
+}
+
+func (p testGatewayProvider) GetIPv6Gateways() ([]myip.Gateway, error) {
+	return p.ipv6Gateways, nil
+}
+
+// getMyGateways should return the selected gateways of the selected family.
+func Test_getMyGateways_SelectLast_LastGatewayIsReturned(t *testing.T) {
+	// arrange
+	gatewayProvider := testGatewayProvider{
+		ipv4Gateways: []myip.Gateway{
+			{IP: net.ParseIP("192.168.1.1"), Interface: "eth0", Metric: 100},
+			{IP: net.ParseIP("10.0.0.1"), Interface: "wlan0", Metric: 600},
+		},
+		ipv6Gateways: []myip.Gateway{
+			{IP: net.ParseIP("fe80::1"), Interface: "eth0", Metric: 1024},
+		},
+	}
+
+	// act
+	gateways, err := getMyGateways(gatewayProvider, "last", true)
+
+	// assert
+	if err != nil {
+		t.Fatalf("getMyGateways returned an error: %s", err.Error())
+	}
+
+	if len(gateways) != 1 || !gateways[0].IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("getMyGateways returned %v but should have returned the gateway 10.0.0.1", gateways)
+	}
+}
+
+// getMyGateways should return an error if no gateway of the selected family is available.
+func Test_getMyGateways_NoGateways_ErrorIsReturned(t *testing.T) {
+	// arrange
+	gatewayProvider := testGatewayProvider{}
+
+	// act
+	_, err := getMyGateways(gatewayProvider, "all", false)
+
+	// assert
+	if err == nil {
+		t.Errorf("getMyGateways did not return an error even though no IPv6 gateways are available")
+	}
+}
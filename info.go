@@ -0,0 +1,190 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// networkInfo contains the local and remote IP addresses of the current machine.
+type networkInfo struct {
+	interfaces      []myip.InterfaceIPs
+	interfacesError error
+
+	localIPv4      []net.IP
+	localIPv4Error error
+
+	localIPv6      []net.IP
+	localIPv6Error error
+
+	remoteIPv4      []net.IP
+	remoteIPv4Error error
+
+	remoteIPv6      []net.IP
+	remoteIPv6Error error
+}
+
+// myInfo determines the local and remote IPv4 and IPv6 addresses concurrently.
+func myInfo() networkInfo {
+
+	var info networkInfo
+	var wg sync.WaitGroup
+	wg.Add(5)
+
+	go func() {
+		defer wg.Done()
+		info.interfaces, info.interfacesError = myip.GetLocalInterfaceIPs()
+	}()
+
+	go func() {
+		defer wg.Done()
+		localIPs, localIPError := myLocalIP(myip.SelectAll, true, myip.LocalIPProviderOptions{}, "")
+		info.localIPv4, info.localIPv4Error = getIPs(localIPs), localIPError
+	}()
+
+	go func() {
+		defer wg.Done()
+		localIPs, localIPError := myLocalIP(myip.SelectAll, false, myip.LocalIPProviderOptions{}, "")
+		info.localIPv6, info.localIPv6Error = getIPs(localIPs), localIPError
+	}()
+
+	go func() {
+		defer wg.Done()
+		info.remoteIPv4, info.remoteIPv4Error = myRemoteIP(myip.SelectAll, true, myip.RemoteIPProviderOptions{}, myip.NetworkFilter{}, ipCache{})
+	}()
+
+	go func() {
+		defer wg.Done()
+		info.remoteIPv6, info.remoteIPv6Error = myRemoteIP(myip.SelectAll, false, myip.RemoteIPProviderOptions{}, myip.NetworkFilter{}, ipCache{})
+	}()
+
+	wg.Wait()
+
+	return info
+}
+
+// isBehindNAT returns true if none of the given remote IPs is assigned locally.
+// If no remote IPs are given false is returned.
+func isBehindNAT(remoteIPs, localIPs []net.IP) bool {
+	if len(remoteIPs) == 0 {
+		return false
+	}
+
+	for _, remoteIP := range remoteIPs {
+		if containsIP(localIPs, remoteIP) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isBehindCGNAT returns true if any of the given IPs is part of the carrier-grade NAT address space.
+func isBehindCGNAT(ips []net.IP) bool {
+	for _, ip := range ips {
+		if myip.GetScope(ip) == myip.ScopeCGNAT {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasGlobalIPv6 returns true if any of the given IPs is a globally routable IPv6 address.
+func hasGlobalIPv6(ips []net.IP) bool {
+	for _, ip := range ips {
+		if ip.To4() == nil && myip.GetScope(ip) == myip.ScopeGlobal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printNetworkInfo writes a human-readable report of the given network information to the given writer.
+func printNetworkInfo(w io.Writer, info networkInfo) {
+
+	// local addresses per interface
+	fmt.Fprintf(w, "Local addresses:\n")
+	if info.interfacesError != nil {
+		fmt.Fprintf(w, "  unavailable (%s)\n", strings.TrimSpace(info.interfacesError.Error()))
+	}
+
+	for _, networkInterface := range info.interfaces {
+		if networkInterface.Err != nil {
+			fmt.Fprintf(w, "  %-12s unavailable (%s)\n", networkInterface.Name, strings.TrimSpace(networkInterface.Err.Error()))
+			continue
+		}
+
+		for index, ip := range networkInterface.IPs {
+			name := ""
+			if index == 0 {
+				name = networkInterface.Name
+			}
+
+			fmt.Fprintf(w, "  %-12s %s\n", name, ip)
+		}
+	}
+
+	fmt.Fprintf(w, "\n")
+
+	// public addresses
+	fmt.Fprintf(w, "%-24s %s\n", "Public IPv4:", formatInfoIPs(info.remoteIPv4, info.remoteIPv4Error))
+	fmt.Fprintf(w, "%-24s %s\n", "Public IPv6:", formatInfoIPs(info.remoteIPv6, info.remoteIPv6Error))
+	fmt.Fprintf(w, "\n")
+
+	// NAT status
+	fmt.Fprintf(w, "%-24s %s\n", "Behind NAT (IPv4):", formatInfoStatus(isBehindNAT(info.remoteIPv4, info.localIPv4), info.remoteIPv4Error))
+	fmt.Fprintf(w, "%-24s %s\n", "Behind NAT (IPv6):", formatInfoStatus(isBehindNAT(info.remoteIPv6, info.localIPv6), info.remoteIPv6Error))
+	fmt.Fprintf(w, "%-24s %s\n", "Behind CGNAT:", formatInfoStatus(isBehindCGNAT(info.localIPv4), info.localIPv4Error))
+	fmt.Fprintf(w, "%-24s %s\n", "IPv6 globally routable:", formatInfoStatus(hasGlobalIPv6(info.remoteIPv6), info.remoteIPv6Error))
+}
+
+// formatInfoIPs returns the given IPs as a comma-separated list or a description of the given error.
+func formatInfoIPs(ips []net.IP, err error) string {
+	if err != nil {
+		return fmt.Sprintf("unavailable (%s)", strings.TrimSpace(err.Error()))
+	}
+
+	var result string
+	for index, ip := range ips {
+		if index > 0 {
+			result += ", "
+		}
+
+		result += ip.String()
+	}
+
+	return result
+}
+
+// formatInfoStatus returns "yes" or "no" for the given status or "unknown" if the given error is set.
+func formatInfoStatus(status bool, err error) string {
+	if err != nil {
+		return "unknown"
+	}
+
+	if status {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// containsIP returns true if the given list of IPs contains the given IP.
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, candidate := range ips {
+		if candidate.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
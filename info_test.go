@@ -0,0 +1,97 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/andreaskoch/myip"
+)
+
+// isBehindNAT should return true if the remote IP is not assigned to any local interface.
+func Test_isBehindNAT_RemoteIPIsNotAssignedLocally_ResultIsTrue(t *testing.T) {
+	// arrange
+	remoteIPs := []net.IP{net.ParseIP("203.0.113.5")}
+	localIPs := []net.IP{net.ParseIP("192.168.1.10")}
+
+	// act
+	result := isBehindNAT(remoteIPs, localIPs)
+
+	// assert
+	if !result {
+		t.Errorf("isBehindNAT(%q, %q) returned false but should have returned true", remoteIPs, localIPs)
+	}
+}
+
+// isBehindNAT should return false if the remote IP is assigned to a local interface.
+func Test_isBehindNAT_RemoteIPIsAssignedLocally_ResultIsFalse(t *testing.T) {
+	// arrange
+	remoteIPs := []net.IP{net.ParseIP("2001:db8::10")}
+	localIPs := []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::10")}
+
+	// act
+	result := isBehindNAT(remoteIPs, localIPs)
+
+	// assert
+	if result {
+		t.Errorf("isBehindNAT(%q, %q) returned true but should have returned false", remoteIPs, localIPs)
+	}
+}
+
+// isBehindCGNAT should only return true for addresses in 100.64.0.0/10.
+func Test_isBehindCGNAT(t *testing.T) {
+	// arrange
+	inputs := map[string]bool{
+		"100.64.0.1":      true,
+		"100.127.255.254": true,
+		"100.128.0.1":     false,
+		"192.168.1.1":     false,
+		"2001:db8::1":     false,
+	}
+
+	for input, expectedResult := range inputs {
+
+		// act
+		result := isBehindCGNAT([]net.IP{net.ParseIP(input)})
+
+		// assert
+		if result != expectedResult {
+			t.Errorf("isBehindCGNAT(%q) returned %v but should have returned %v", input, result, expectedResult)
+		}
+	}
+}
+
+// printNetworkInfo should report interfaces whose addresses could not be read
+// and still print the addresses of the other interfaces.
+func Test_printNetworkInfo_InterfaceFailed_ErrorIsPrintedForInterface(t *testing.T) {
+	// arrange
+	info := networkInfo{
+		interfaces: []myip.InterfaceIPs{
+			{Name: "eth0", IPs: []net.IP{net.ParseIP("192.168.1.10")}},
+			{Name: "veth1", Err: fmt.Errorf("no such network interface")},
+		},
+	}
+	output := new(bytes.Buffer)
+
+	// act
+	printNetworkInfo(output, info)
+
+	// assert
+	if !strings.Contains(output.String(), "192.168.1.10") {
+		t.Errorf("printNetworkInfo did not print the address of eth0: %q", output.String())
+	}
+
+	if !strings.Contains(output.String(), "veth1        unavailable (no such network interface)") {
+		t.Errorf("printNetworkInfo did not print the error of veth1: %q", output.String())
+	}
+
+	if strings.Contains(output.String(), "<nil>") {
+		t.Errorf("printNetworkInfo printed a <nil> address: %q", output.String())
+	}
+}
@@ -9,10 +9,10 @@ import (
 	"fmt"
 	"github.com/andreaskoch/myip"
 	"net"
+	"net/url"
 	"os"
-	"regexp"
-	"strconv"
 	"strings"
+	"time"
 )
 
 // GitInfo is either the empty string (the default)
@@ -20,7 +20,7 @@ import (
 // using the -X linker flag (Example: "2015-01-11-284c030+")
 var GitInfo string
 
-// commandOptions is the flag set for the "local" and "remote" actions
+// commandOptions is the flag set for the "local", "remote" and "gateway" actions
 var commandOptions = flag.NewFlagSet("command-options", flag.ExitOnError)
 
 // useIPv4 contains a flag inidicating whether IPv4 addresses should be used (default: false)
@@ -29,14 +29,92 @@ var useIPv4 bool
 // ipSelectionOption specifies the IP address that shall be returned if there are multiple addresses available
 var ipSelectionOption string
 
-// ipSelectionOptionIndexPattern defines the pattern for the index-based IP selection (e.g. "1,2,3")
-var ipSelectionOptionIndexPattern = regexp.MustCompile(`^(\d+,*)+$`)
+var ipSelectionOptions = []string{myip.SelectAll, myip.SelectFirst, myip.SelectLast, "1", "1,3", "2-4", "3-", "-1", "1--2", myip.SelectUnique}
 
-const ipSelectionOptionAll = "all"
-const ipSelectionOptionFirst = "first"
-const ipSelectionOptionLast = "last"
+// ipScopeOption contains a comma-separated list of address scopes the local IPs are filtered by (e.g. "global,ula,private")
+var ipScopeOption string
 
-var ipSelectionOptions = []string{ipSelectionOptionAll, ipSelectionOptionFirst, ipSelectionOptionLast, "1", "1,3"}
+// includeLoopback contains a flag indicating whether local loopback addresses should be returned (default: false)
+var includeLoopback bool
+
+// includeLinkLocal contains a flag indicating whether local link-local addresses should be returned (default: false)
+var includeLinkLocal bool
+
+// usePrimaryIP contains a flag indicating whether only the primary outbound address should be returned (default: false)
+var usePrimaryIP bool
+
+// destinationOption contains the destination address used for determining the primary outbound address
+// and for ordering the local addresses by RFC 6724
+var destinationOption string
+
+// orderOption specifies how the local IPs are sorted (e.g. "numeric", "rfc6724")
+var orderOption string
+
+// watchLocalIPs contains a flag indicating whether the local IPs should be watched for changes (default: false)
+var watchLocalIPs bool
+
+// networkNamespaceOption contains the name or path of the Linux network namespace the local IPs are read from
+var networkNamespaceOption string
+
+// excludeFlagsOption contains a comma-separated list of address flags; local IPs with any of these flags are ignored (e.g. "temporary,deprecated")
+var excludeFlagsOption string
+
+// inNetworksOption contains a comma-separated list of networks the IPs are restricted to (e.g. "10.0.0.0/8,fd00::/8")
+var inNetworksOption string
+
+// notInNetworksOption contains a comma-separated list of networks whose IPs are ignored (e.g. "172.17.0.0/16")
+var notInNetworksOption string
+
+// equalsOption contains the IP address the "check" action expects (e.g. "203.0.113.5")
+var equalsOption string
+
+// checkHasGlobalIPv6 contains a flag indicating whether the "check" action expects a globally routable IPv6 address (default: false)
+var checkHasGlobalIPv6 bool
+
+// checkMatchesLocal contains a flag indicating whether the "check" action expects an address that is assigned to a local interface (default: false)
+var checkMatchesLocal bool
+
+// remoteMethodOption contains a comma-separated list of methods used for determining the remote IPs (e.g. "dns,http")
+var remoteMethodOption string
+
+// strategyOption specifies how the answers of the remote services are combined (e.g. "race", "fallback")
+var strategyOption string
+
+// proxyOption contains the URL of the proxy the remote services are requested through (e.g. "socks5://127.0.0.1:9050")
+var proxyOption string
+
+// ipv4ProxyOption contains the URL of the proxy the remote services are requested through for IPv4 (default: proxyOption)
+var ipv4ProxyOption string
+
+// ipv6ProxyOption contains the URL of the proxy the remote services are requested through for IPv6 (default: proxyOption)
+var ipv6ProxyOption string
+
+// sourceInterfaceOption contains the name of the network interface the remote services are asked through (e.g. "wg0")
+var sourceInterfaceOption string
+
+// sourceAddressOption contains the local address the remote services are asked from (e.g. "10.8.0.2")
+var sourceAddressOption string
+
+// retries contains the number of times a failed request to a remote service is repeated (default: 0)
+var retries int
+
+// retryBackoff contains the delay before the first retry; it doubles with every retry (default: 1s)
+var retryBackoff time.Duration
+
+// cacheTTL contains the duration the remote IPs are cached on disk for (default: 0, no cache)
+var cacheTTL time.Duration
+
+// refreshCache contains a flag indicating whether the cached remote IPs should be ignored and updated (default: false)
+var refreshCache bool
+
+// perInterface contains a flag indicating whether the remote IPs should be determined for each local interface (default: false)
+var perInterface bool
+
+// fromSnapshotOption contains the path of a snapshot file the local IPs are read from instead of the network interfaces
+var fromSnapshotOption string
+
+// showSubnets contains a flag indicating whether the subnet details of the local IPs should be returned (default: false)
+var showSubnets bool
 
 // actionnamelocal contains the name of the "local" action
 const actionnamelocal = "local"
@@ -44,6 +122,18 @@ const actionnamelocal = "local"
 // actionnameremote contains the name of the "remote" action
 const actionnameremote = "remote"
 
+// actionnameinfo contains the name of the "info" action
+const actionnameinfo = "info"
+
+// actionnamegateway contains the name of the "gateway" action
+const actionnamegateway = "gateway"
+
+// actionnamecheck contains the name of the "check" action
+const actionnamecheck = "check"
+
+// actionnamesnapshot contains the name of the "snapshot" action
+const actionnamesnapshot = "snapshot"
+
 // The ipAddresser interface provides functions for
 // retrieving IPv4 and IPv6 addresses.
 type ipAddresser interface {
@@ -51,6 +141,13 @@ type ipAddresser interface {
 	ipv6Addresser
 }
 
+// The ipAddrAddresser interface provides functions for
+// retrieving IPv4 and IPv6 addresses including their zones.
+type ipAddrAddresser interface {
+	GetIPv4IPAddrs() ([]net.IPAddr, error)
+	GetIPv6IPAddrs() ([]net.IPAddr, error)
+}
+
 // The ipv6Addresser interface provides functions for
 // retrieving IPv6 addresses.
 type ipv6Addresser interface {
@@ -69,7 +166,35 @@ func main() {
 	executableName := arguments[0]
 
 	commandOptions.BoolVar(&useIPv4, "4", false, fmt.Sprintf("Use IPv4 instead of IPv6"))
-	commandOptions.StringVar(&ipSelectionOption, "select", ipSelectionOptionAll, fmt.Sprintf("Select one or more IPs (\"%s\")", strings.Join(ipSelectionOptions, `", "`)))
+	commandOptions.StringVar(&ipSelectionOption, "select", myip.SelectAll, fmt.Sprintf("Select one or more IPs (\"%s\")", strings.Join(ipSelectionOptions, `", "`)))
+	commandOptions.StringVar(&ipScopeOption, "scope", "", fmt.Sprintf("Only return local IPs with the given scopes (\"%s\")", strings.Join(myip.ScopeNames(), `", "`)))
+	commandOptions.BoolVar(&includeLoopback, "include-loopback", false, fmt.Sprintf("Include local loopback addresses (e.g. 127.0.0.1, ::1)"))
+	commandOptions.BoolVar(&includeLinkLocal, "include-link-local", false, fmt.Sprintf("Include local link-local addresses (e.g. fe80::1%%eth0)"))
+	commandOptions.BoolVar(&usePrimaryIP, "primary", false, fmt.Sprintf("Return the local IP used for outbound traffic to the destination"))
+	commandOptions.StringVar(&destinationOption, "destination", "", fmt.Sprintf("The destination for determining the primary IP or the RFC 6724 order (default: %s, %s)", myip.DefaultIPv4Destination, myip.DefaultIPv6Destination))
+	commandOptions.BoolVar(&watchLocalIPs, "watch", false, fmt.Sprintf("Print local IP changes as they happen (Linux only)"))
+	commandOptions.StringVar(&networkNamespaceOption, "netns", "", fmt.Sprintf("Read the local IPs from the given network namespace (Linux only, e.g. \"blue\", \"/proc/1234/ns/net\")"))
+	commandOptions.StringVar(&excludeFlagsOption, "exclude-flags", "", fmt.Sprintf("Ignore local IPs with any of the given address flags (Linux only, e.g. \"temporary,deprecated\")"))
+	commandOptions.StringVar(&inNetworksOption, "in", "", fmt.Sprintf("Only return IPs in the given networks (e.g. \"10.0.0.0/8,fd00::/8\")"))
+	commandOptions.StringVar(&notInNetworksOption, "not-in", "", fmt.Sprintf("Ignore IPs in the given networks (e.g. \"172.17.0.0/16\")"))
+	commandOptions.StringVar(&fromSnapshotOption, "from-snapshot", "", fmt.Sprintf("Read the local IPs from a snapshot file created by the \"%s\" action instead of the network interfaces", actionnamesnapshot))
+	commandOptions.BoolVar(&showSubnets, "subnet", false, fmt.Sprintf("Print the network, broadcast address, host range and netmask of the local IPs"))
+	commandOptions.StringVar(&equalsOption, "equals", "", fmt.Sprintf("check: Require one of the IPs to be the given IP (e.g. \"203.0.113.5\")"))
+	commandOptions.BoolVar(&checkHasGlobalIPv6, "has-global-ipv6", false, fmt.Sprintf("check: Require one of the IPs to be a globally routable IPv6 address"))
+	commandOptions.BoolVar(&checkMatchesLocal, "matches-local", false, fmt.Sprintf("check: Require one of the IPs to be assigned to a local interface (e.g. the public IP without NAT)"))
+	commandOptions.StringVar(&remoteMethodOption, "method", myip.RemoteMethodHTTP.String(), fmt.Sprintf("Ask the remote services of the given methods in the given order (\"%s\", e.g. \"dns,http\")", strings.Join(myip.RemoteMethodNames(), `", "`)))
+	commandOptions.StringVar(&strategyOption, "strategy", myip.StrategyRace.String(), fmt.Sprintf("Combine the answers of the remote services with the given strategy (\"%s\")", strings.Join(myip.StrategyNames(), `", "`)))
+	commandOptions.StringVar(&proxyOption, "proxy", "", fmt.Sprintf("Request the remote web services through the given proxy (default: HTTPS_PROXY, e.g. \"http://proxy:3128\", \"socks5://127.0.0.1:9050\")"))
+	commandOptions.StringVar(&ipv4ProxyOption, "ipv4-proxy", "", fmt.Sprintf("Request the remote web services through the given proxy for IPv4 (default: -proxy)"))
+	commandOptions.StringVar(&ipv6ProxyOption, "ipv6-proxy", "", fmt.Sprintf("Request the remote web services through the given proxy for IPv6 (default: -proxy)"))
+	commandOptions.StringVar(&sourceInterfaceOption, "source-interface", "", fmt.Sprintf("Ask the remote services through the given network interface (e.g. \"wg0\")"))
+	commandOptions.StringVar(&sourceAddressOption, "source-address", "", fmt.Sprintf("Ask the remote services from the given local address (e.g. \"10.8.0.2\")"))
+	commandOptions.IntVar(&retries, "retries", 0, fmt.Sprintf("Repeat failed requests to the remote services up to the given number of times (at most %d; timeouts, connection resets, 5xx)", myip.MaxRetries))
+	commandOptions.DurationVar(&retryBackoff, "retry-backoff", myip.DefaultRetryBackoff, fmt.Sprintf("The delay before the first retry; it doubles with every retry (with a random jitter of +/-50%%)"))
+	commandOptions.DurationVar(&cacheTTL, "cache", 0, fmt.Sprintf("Return the cached remote IPs if they are younger than the given duration and cache new answers (e.g. \"5m\")"))
+	commandOptions.BoolVar(&refreshCache, "refresh", false, fmt.Sprintf("Ask the remote services even if the cached remote IPs are fresh and update the cache"))
+	commandOptions.BoolVar(&perInterface, "per-interface", false, fmt.Sprintf("Print the remote IP of each local interface and address with the given scopes (default: \"global,private,ula,cgnat\")"))
+	commandOptions.StringVar(&orderOption, "sort", myip.OrderNone.String(), fmt.Sprintf("Sort the local IPs (\"%s\")", strings.Join(myip.OrderNames(), `", "`)))
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s returns your local IPv6 (or IPv4) address.\n", executableName)
@@ -79,11 +204,16 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Usage:\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "  %s <action> [options]\n", executableName)
+		fmt.Fprintf(os.Stderr, "  %s %s <%s|%s> [options]\n", executableName, actionnamecheck, actionnamelocal, actionnameremote)
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Actions:\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "%10s  %s\n", actionnamelocal, "Get your local IP address")
 		fmt.Fprintf(os.Stderr, "%10s  %s\n", actionnameremote, "Get your remote IP address")
+		fmt.Fprintf(os.Stderr, "%10s  %s\n", actionnameinfo, "Get a report of your local and remote IP addresses and NAT status")
+		fmt.Fprintf(os.Stderr, "%10s  %s\n", actionnamegateway, "Get your default gateway, its interface and metric (Linux only)")
+		fmt.Fprintf(os.Stderr, "%10s  %s\n", actionnamesnapshot, "Print a snapshot of your network interfaces and their addresses (JSON) for -from-snapshot")
+		fmt.Fprintf(os.Stderr, "%10s  %s\n", actionnamecheck, "Exit with 0 if your local or remote IP addresses meet the given conditions, 1 otherwise")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fmt.Fprintf(os.Stderr, "\n")
@@ -98,20 +228,210 @@ func main() {
 		os.Exit(1)
 	}
 
+	// the "check" action is followed by the name of the checked addresses (local, remote)
+	actionName := strings.TrimSpace(strings.ToLower(arguments[1]))
+	optionArguments := arguments[2:]
+
+	var checkSourceName string
+	if actionName == actionnamecheck {
+		if len(arguments) < 3 {
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		checkSourceName = strings.TrimSpace(strings.ToLower(arguments[2]))
+		optionArguments = arguments[3:]
+	}
+
 	// parse the command line options
-	commandOptions.Parse(arguments[2:])
+	commandOptions.Parse(optionArguments)
+
+	filter, filterError := myip.ParseNetworkFilter(inNetworksOption, notInNetworksOption)
+	if filterError != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", filterError.Error())
+		os.Exit(1)
+	}
 
 	// action: remote vs. local
-	var ips []net.IP
+	var ips []net.IPAddr
 	var myIPError error
 
-	actionName := strings.TrimSpace(strings.ToLower(arguments[1]))
 	switch actionName {
 	case actionnamelocal:
-		ips, myIPError = myLocalIP(ipSelectionOption, useIPv4)
+		if networkNamespaceOption != "" && (usePrimaryIP || watchLocalIPs) {
+			fmt.Fprintf(os.Stderr, "The -netns option cannot be combined with -primary or -watch.\n")
+			os.Exit(1)
+		}
+
+		if fromSnapshotOption != "" && (usePrimaryIP || watchLocalIPs || networkNamespaceOption != "" || excludeFlagsOption != "") {
+			fmt.Fprintf(os.Stderr, "The -from-snapshot option cannot be combined with -primary, -watch, -netns or -exclude-flags.\n")
+			os.Exit(1)
+		}
+
+		if watchLocalIPs && (ipSelectionOption != myip.SelectAll || orderOption != myip.OrderNone.String()) {
+			fmt.Fprintf(os.Stderr, "The -watch option cannot be combined with -select or -sort.\n")
+			os.Exit(1)
+		}
+
+		if showSubnets && (usePrimaryIP || watchLocalIPs) {
+			fmt.Fprintf(os.Stderr, "The -subnet option cannot be combined with -primary or -watch.\n")
+			os.Exit(1)
+		}
+
+		if usePrimaryIP {
+			primaryIPs, primaryIPError := myPrimaryIP(ipSelectionOption, useIPv4, destinationOption, filter)
+			ips, myIPError = getIPAddrs(primaryIPs), primaryIPError
+			break
+		}
+
+		localIPOptions, optionsError := getLocalIPOptions(ipScopeOption, orderOption, destinationOption, excludeFlagsOption)
+		if optionsError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", optionsError.Error())
+			os.Exit(1)
+		}
+
+		localIPOptions.Networks, localIPOptions.ExcludedNetworks = filter.Networks, filter.ExcludedNetworks
+
+		if watchLocalIPs {
+			myIPError = watchLocalIP(os.Stdout, useIPv4, localIPOptions)
+			break
+		}
+
+		localIPOptions.NetworkNamespace = networkNamespaceOption
+
+		if showSubnets {
+			subnets, subnetError := myLocalSubnets(ipSelectionOption, useIPv4, localIPOptions, fromSnapshotOption)
+			if subnetError != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", subnetError.Error())
+				os.Exit(1)
+			}
+
+			printSubnets(os.Stdout, subnets)
+			return
+		}
+
+		ips, myIPError = myLocalIP(ipSelectionOption, useIPv4, localIPOptions, fromSnapshotOption)
 
 	case actionnameremote:
-		ips, myIPError = myRemoteIP(ipSelectionOption, useIPv4)
+		remoteIPOptions, optionsError := getRemoteIPOptions(remoteMethodOption, strategyOption, proxyOption, ipv4ProxyOption, ipv6ProxyOption, sourceInterfaceOption, sourceAddressOption, retries, retryBackoff)
+		if optionsError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", optionsError.Error())
+			os.Exit(1)
+		}
+
+		cache, cacheError := getIPCache(cacheTTL, refreshCache)
+		if cacheError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", cacheError.Error())
+			os.Exit(1)
+		}
+
+		if perInterface {
+			if cacheTTL != 0 {
+				fmt.Fprintf(os.Stderr, "The -per-interface option cannot be combined with -cache.\n")
+				os.Exit(1)
+			}
+
+			if sourceInterfaceOption != "" || sourceAddressOption != "" {
+				fmt.Fprintf(os.Stderr, "The -per-interface option cannot be combined with -source-interface or -source-address.\n")
+				os.Exit(1)
+			}
+
+			if ipSelectionOption != myip.SelectAll {
+				fmt.Fprintf(os.Stderr, "The -per-interface option cannot be combined with -select.\n")
+				os.Exit(1)
+			}
+
+			scopes, scopeError := getScopes(ipScopeOption)
+			if scopeError != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", scopeError.Error())
+				os.Exit(1)
+			}
+
+			uplinks, uplinkError := myUplinkIPs(useIPv4, scopes, filter, remoteIPOptions)
+			if uplinkError != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", uplinkError.Error())
+				os.Exit(1)
+			}
+
+			printUplinkIPs(os.Stdout, uplinks)
+			if !hasUplinkIP(uplinks) {
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		remoteIPs, remoteIPError := myRemoteIP(ipSelectionOption, useIPv4, remoteIPOptions, filter, cache)
+		ips, myIPError = getIPAddrs(remoteIPs), remoteIPError
+
+	case actionnameinfo:
+		printNetworkInfo(os.Stdout, myInfo())
+		return
+
+	case actionnamecheck:
+		predicates, predicatesError := getCheckPredicates(equalsOption, checkHasGlobalIPv6, checkMatchesLocal)
+		if predicatesError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", predicatesError.Error())
+			os.Exit(1)
+		}
+
+		localIPOptions, localOptionsError := getLocalIPOptions(ipScopeOption, orderOption, destinationOption, excludeFlagsOption)
+		if localOptionsError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", localOptionsError.Error())
+			os.Exit(1)
+		}
+
+		localIPOptions.Networks, localIPOptions.ExcludedNetworks = filter.Networks, filter.ExcludedNetworks
+		localIPOptions.NetworkNamespace = networkNamespaceOption
+
+		remoteIPOptions, remoteOptionsError := getRemoteIPOptions(remoteMethodOption, strategyOption, proxyOption, ipv4ProxyOption, ipv6ProxyOption, sourceInterfaceOption, sourceAddressOption, retries, retryBackoff)
+		if remoteOptionsError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", remoteOptionsError.Error())
+			os.Exit(1)
+		}
+
+		cache, cacheError := getIPCache(cacheTTL, refreshCache)
+		if cacheError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", cacheError.Error())
+			os.Exit(1)
+		}
+
+		options := checkOptions{
+			selection:       ipSelectionOption,
+			filter:          filter,
+			localIPOptions:  localIPOptions,
+			snapshotPath:    fromSnapshotOption,
+			remoteIPOptions: remoteIPOptions,
+			cache:           cache,
+		}
+
+		if checkError := myCheck(checkSourceName, useIPv4, options, predicates); checkError != nil {
+			fmt.Fprintf(os.Stderr, "Check failed: %s\n", strings.TrimSpace(checkError.Error()))
+			os.Exit(1)
+		}
+
+		return
+
+	case actionnamesnapshot:
+		if snapshotError := writeSnapshot(os.Stdout); snapshotError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", snapshotError.Error())
+			os.Exit(1)
+		}
+
+		return
+
+	case actionnamegateway:
+		gateways, gatewayError := myGateway(ipSelectionOption, useIPv4)
+		if gatewayError != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", gatewayError.Error())
+			os.Exit(1)
+		}
+
+		for _, gateway := range gateways {
+			fmt.Fprintf(os.Stdout, "%s\n", formatGateway(gateway))
+		}
+
+		return
 
 	default:
 		{
@@ -129,28 +449,173 @@ func main() {
 
 	// print IPs
 	for _, ip := range ips {
-		fmt.Fprintf(os.Stdout, "%s\n", ip)
+		fmt.Fprintf(os.Stdout, "%s\n", ip.String())
 	}
 
 }
 
-// myLocalIP returns the current local IPv6 (or IPv4) address
-func myLocalIP(selectionOption string, useIPv4 bool) ([]net.IP, error) {
+// myLocalIP returns the current local IPv6 (or IPv4) address.
+// The local addresses are filtered according to the given options.
+// If a snapshot file is given, the addresses are read from the snapshot.
+func myLocalIP(selectionOption string, useIPv4 bool, options myip.LocalIPProviderOptions, snapshotPath string) ([]net.IPAddr, error) {
 
-	ipProvider, ipProviderError := myip.NewLocalIPProvider()
+	ipProvider, ipProviderError := newMyLocalIPProvider(options, snapshotPath)
 	if ipProviderError != nil {
 		return nil, fmt.Errorf("%s\n", ipProviderError.Error())
 	}
 
-	return getMyIP(ipProvider, selectionOption, useIPv4)
+	return getMyIPAddrs(ipProvider, selectionOption, useIPv4)
+}
+
+// myPrimaryIP returns the local IPv6 (or IPv4) address that is used
+// for outbound traffic to the given destination (default: a public address)
+// if it passes the given network filter.
+func myPrimaryIP(selectionOption string, useIPv4 bool, destination string, filter myip.NetworkFilter) ([]net.IP, error) {
+
+	destinationIP, destinationError := getDestinationIP(destination)
+	if destinationError != nil {
+		return nil, destinationError
+	}
+
+	ipProvider := myip.NewPrimaryIPProvider(destinationIP, destinationIP)
+
+	return getMyIP(filteredIPAddresser{ipProvider, filter}, selectionOption, useIPv4)
+}
+
+// myRemoteIP returns the current remote IPv6 (or IPv4) addresses that pass the given network filter.
+// The remote services are asked according to the given options unless the given cache contains fresh addresses.
+func myRemoteIP(selectionOption string, useIPv4 bool, options myip.RemoteIPProviderOptions, filter myip.NetworkFilter, cache ipCache) ([]net.IP, error) {
+
+	ipProvider := newCachedIPAddresser(myip.NewRemoteIPProviderWithOptions(options), cache, getRemoteIPCacheKey(options))
+
+	return getMyIP(filteredIPAddresser{ipProvider, filter}, selectionOption, useIPv4)
+}
+
+// getLocalIPOptions returns the options for the local IP provider
+// from the given scope, order, destination and address flag options.
+func getLocalIPOptions(scopeOption, orderOption, destinationOption, excludeFlagsOption string) (myip.LocalIPProviderOptions, error) {
+
+	scopes, scopeError := getScopes(scopeOption)
+	if scopeError != nil {
+		return myip.LocalIPProviderOptions{}, scopeError
+	}
+
+	order, orderError := myip.ParseOrder(orderOption)
+	if orderError != nil {
+		return myip.LocalIPProviderOptions{}, orderError
+	}
+
+	destination, destinationError := getDestinationIP(destinationOption)
+	if destinationError != nil {
+		return myip.LocalIPProviderOptions{}, destinationError
+	}
+
+	var excludeFlags myip.AddressFlags
+	if excludeFlagsOption != "" {
+		var excludeFlagsError error
+		excludeFlags, excludeFlagsError = myip.ParseAddressFlags(excludeFlagsOption)
+		if excludeFlagsError != nil {
+			return myip.LocalIPProviderOptions{}, excludeFlagsError
+		}
+	}
+
+	return myip.LocalIPProviderOptions{
+		Scopes:           scopes,
+		IncludeLoopback:  includeLoopback,
+		IncludeLinkLocal: includeLinkLocal,
+		Order:            order,
+		Destination:      destination,
+		ExcludeFlags:     excludeFlags,
+	}, nil
+}
+
+// getRemoteIPOptions returns the options for the remote IP provider
+// from the given method, strategy, proxy, source and retry options.
+func getRemoteIPOptions(methodOption, strategyOption, proxyOption, ipv4ProxyOption, ipv6ProxyOption, sourceInterfaceOption, sourceAddressOption string, retries int, retryBackoff time.Duration) (myip.RemoteIPProviderOptions, error) {
+
+	methods, methodError := myip.ParseRemoteMethods(methodOption)
+	if methodError != nil {
+		return myip.RemoteIPProviderOptions{}, methodError
+	}
+
+	strategy, strategyError := myip.ParseStrategy(strategyOption)
+	if strategyError != nil {
+		return myip.RemoteIPProviderOptions{}, strategyError
+	}
+
+	var proxyURLs []*url.URL
+	for _, option := range []string{proxyOption, ipv4ProxyOption, ipv6ProxyOption} {
+		if option == "" {
+			proxyURLs = append(proxyURLs, nil)
+			continue
+		}
+
+		proxyURL, proxyError := myip.ParseProxyURL(option)
+		if proxyError != nil {
+			return myip.RemoteIPProviderOptions{}, proxyError
+		}
+
+		proxyURLs = append(proxyURLs, proxyURL)
+	}
+
+	var sourceAddress net.IP
+	if sourceAddressOption != "" {
+		sourceAddress = net.ParseIP(sourceAddressOption)
+		if sourceAddress == nil {
+			return myip.RemoteIPProviderOptions{}, fmt.Errorf("%q is not a valid source IP address", sourceAddressOption)
+		}
+	}
+
+	if retries < 0 || retries > myip.MaxRetries {
+		return myip.RemoteIPProviderOptions{}, fmt.Errorf("The number of retries (%d) must be between 0 and %d", retries, myip.MaxRetries)
+	}
+
+	if retryBackoff <= 0 {
+		return myip.RemoteIPProviderOptions{}, fmt.Errorf("The retry backoff (%s) must be positive", retryBackoff)
+	}
+
+	options := myip.RemoteIPProviderOptions{
+		Methods:         methods,
+		Strategy:        strategy,
+		Proxy:           proxyURLs[0],
+		IPv4Proxy:       proxyURLs[1],
+		IPv6Proxy:       proxyURLs[2],
+		SourceInterface: sourceInterfaceOption,
+		SourceAddress:   sourceAddress,
+		Retries:         retries,
+		RetryBackoff:    retryBackoff,
+	}
+
+	if err := options.Validate(); err != nil {
+		return myip.RemoteIPProviderOptions{}, err
+	}
+
+	return options, nil
 }
 
-// myRemoteIP returns the current remote IPv6 (or IPv4) address
-func myRemoteIP(selectionOption string, useIPv4 bool) ([]net.IP, error) {
+// getDestinationIP parses the given destination IP address.
+// If the given destination is empty no IP is returned.
+func getDestinationIP(destination string) (net.IP, error) {
+	if destination == "" {
+		return nil, nil
+	}
+
+	destinationIP := net.ParseIP(destination)
+	if destinationIP == nil {
+		return nil, fmt.Errorf("%q is not a valid destination IP address", destination)
+	}
 
-	ipProvider := myip.NewRemoteIPProvider()
+	return destinationIP, nil
+}
+
+// getScopes parses the given comma-separated list of scope names.
+// If the given list is empty no scopes are returned.
+func getScopes(scopeOption string) ([]myip.Scope, error) {
+	if scopeOption == "" {
+		return nil, nil
+	}
 
-	return getMyIP(ipProvider, selectionOption, useIPv4)
+	return myip.ParseScopes(scopeOption)
 }
 
 // getMyIP returns the selected IPv6 or IPv4 addresses from the given IP provider.
@@ -172,16 +637,16 @@ func getMyIP(ipProvider ipAddresser, selectionOption string, useIPv4 bool) ([]ne
 
 	// abort if no IPs are returned
 	if len(allIPs) == 0 {
-		ipType := "IPv6"
-		if useIPv4 {
-			ipType = "IPv4"
-		}
-
-		return []net.IP{}, fmt.Errorf("No %s IPs available.", ipType)
+		return []net.IP{}, fmt.Errorf("No %s IPs available.", getIPType(useIPv4))
 	}
 
 	// select one or more IPs
-	selectedIPs, ipSelectionError := getSelectedIPs(allIPs, selectionOption)
+	selection, selectionError := myip.ParseSelection(selectionOption)
+	if selectionError != nil {
+		return nil, fmt.Errorf("%s\n", selectionError.Error())
+	}
+
+	selectedIPs, ipSelectionError := selection.SelectIPs(allIPs)
 	if ipSelectionError != nil {
 		return nil, fmt.Errorf("%s\n", ipSelectionError.Error())
 	}
@@ -189,74 +654,69 @@ func getMyIP(ipProvider ipAddresser, selectionOption string, useIPv4 bool) ([]ne
 	return selectedIPs, nil
 }
 
-// getSelectedIPs returns a subset of the given IPs based on the given selection option (all, fist, last, "1,2", ...).
-// If the given selection option is invalid an error will be returned.
-func getSelectedIPs(ips []net.IP, selectionOption string) ([]net.IP, error) {
-
-	// abort if no IPs have been supplied
-	if len(ips) == 0 {
-
-		// If there was a selection given, an empty IP slice is an error
-		selectionGiven := len(selectionOption) > 0
-		selectOptionIsNotAll := selectionOption != ipSelectionOptionAll
-		if selectionGiven && selectOptionIsNotAll {
-			return []net.IP{}, fmt.Errorf("Invalid selection %q. No IPs available.", selectionOption)
-		}
-
-		// no error
-		return []net.IP{}, nil
+// getMyIPAddrs returns the selected IPv6 or IPv4 addresses (including their zones) from the given IP provider.
+func getMyIPAddrs(ipProvider ipAddrAddresser, selectionOption string, useIPv4 bool) ([]net.IPAddr, error) {
 
+	// IPv6 vs IPv4
+	var allIPs []net.IPAddr
+	var ipErr error
+	if useIPv4 {
+		allIPs, ipErr = ipProvider.GetIPv4IPAddrs()
+	} else {
+		allIPs, ipErr = ipProvider.GetIPv6IPAddrs()
 	}
 
-	// handle special options: all, first, last
-	switch {
-	case selectionOption == ipSelectionOptionAll:
-		return ips, nil
-
-	case selectionOption == ipSelectionOptionFirst:
-		return ips[:1], nil
+	// handle errors
+	if ipErr != nil {
+		return nil, fmt.Errorf("%s\n", ipErr.Error())
+	}
 
-	case selectionOption == ipSelectionOptionLast:
-		{
-			lastIPIndex := len(ips) - 1
-			return ips[lastIPIndex:], nil
-		}
+	// abort if no IPs are returned
+	if len(allIPs) == 0 {
+		return []net.IPAddr{}, fmt.Errorf("No %s IPs available.", getIPType(useIPv4))
+	}
 
-	case ipSelectionOptionIndexPattern.MatchString(selectionOption):
-		{
-			break
-		}
+	// select one or more IPs
+	selection, selectionError := myip.ParseSelection(selectionOption)
+	if selectionError != nil {
+		return nil, fmt.Errorf("%s\n", selectionError.Error())
+	}
 
-	default:
-		{
-			return []net.IP{}, fmt.Errorf("%q is not a valid value for the IP selection", ipSelectionOption)
-		}
+	selectedIPs, ipSelectionError := selection.SelectIPAddrs(allIPs)
+	if ipSelectionError != nil {
+		return nil, fmt.Errorf("%s\n", ipSelectionError.Error())
 	}
 
-	// handle indexed selection
-	var selectedIPs []net.IP
-	selectedIndizes := strings.Split(selectionOption, ",")
-	for _, indexString := range selectedIndizes {
+	return selectedIPs, nil
+}
 
-		// parse the string
-		index64, err := strconv.ParseInt(indexString, 10, 64)
-		if err != nil {
-			return []net.IP{}, fmt.Errorf("Invalid IP selection index supplied (min: 1, max: %d).\n", len(ips))
-		}
+// getIPType returns the name of the IP family ("IPv4" or "IPv6").
+func getIPType(useIPv4 bool) string {
+	if useIPv4 {
+		return "IPv4"
+	}
 
-		index := int(index64)
+	return "IPv6"
+}
 
-		// verify the index
-		if index < 1 || index > len(ips) {
-			return []net.IP{}, fmt.Errorf("Invalid IP selection index supplied (min: 1, max: %d).\n", len(ips))
-		}
+// getIPAddrs returns the given IPs as IP addresses without zones.
+func getIPAddrs(ips []net.IP) []net.IPAddr {
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: ip})
+	}
 
-		// append the selected IP
-		selectedIPs = append(selectedIPs, ips[index-1])
+	return addrs
+}
 
+// getIPs returns the IPs of the given IP addresses without their zones.
+func getIPs(addrs []net.IPAddr) []net.IP {
+	var ips []net.IP
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
 	}
 
-	return selectedIPs, nil
+	return ips
 }
 
 // version returns the git version of this binary (e.g. "2015-01-11-284c030+").
@@ -6,8 +6,11 @@ package main
 
 import (
 	"fmt"
+	"github.com/andreaskoch/myip"
+	"math"
 	"net"
 	"testing"
+	"time"
 )
 
 type testIPProvider struct {
@@ -26,6 +29,22 @@ func (p testIPProvider) GetIPv4Addresses() ([]net.IP, error) {
 	return p.ipv4IPs, p.ipv4Err
 }
 
+type testIPAddrProvider struct {
+	ipv4IPs []net.IPAddr
+	ipv4Err error
+
+	ipv6IPs []net.IPAddr
+	ipv6Err error
+}
+
+func (p testIPAddrProvider) GetIPv6IPAddrs() ([]net.IPAddr, error) {
+	return p.ipv6IPs, p.ipv6Err
+}
+
+func (p testIPAddrProvider) GetIPv4IPAddrs() ([]net.IPAddr, error) {
+	return p.ipv4IPs, p.ipv4Err
+}
+
 // getMyIP should return the IPv4 addresses of the IP provider if the useIPv4 flag is set to true.
 func Test_getMyIP_UseIPv4True_IPProviderHasIPv4Addresses_IPv4AddressesAreReturned(t *testing.T) {
 	// arrange
@@ -177,315 +196,269 @@ func Test_getMyIP_UseIPv4True_IPProviderReturnsError_NoIPsAreReturned_ErrorIsRet
 
 }
 
-// If no IPs are supplied and no select option no error should be returned.
-func Test_getSelectedIPs_NoIPsSupplied_NoSelectOptionSupplied_ResultIsEmpty_NoError(t *testing.T) {
-	// arrange
-	ips := []net.IP{}
-	selectOption := ""
-
+// getScopes should not return any scopes if the scope option is empty.
+func Test_getScopes_EmptyOption_NoScopesAreReturned(t *testing.T) {
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	scopes, err := getScopes("")
 
 	// assert
-	if len(selectedIPs) > 0 {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q. But the result should be empty.", ips, selectOption, selectedIPs)
-	}
-
-	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not have returned %q.", ips, selectOption, err.Error())
+	if len(scopes) > 0 || err != nil {
+		t.Errorf("getScopes(%q) returned %v, %v but should have returned no scopes and no error", "", scopes, err)
 	}
 }
 
-// If no IPs are supplied but a select option is given an error should be returned.
-func Test_getSelectedIPs_NoIPsSupplied_SelectOptionAllSupplied_NoErrorIsReturned(t *testing.T) {
+// getScopes should return the scopes in the given order.
+func Test_getScopes_ValidOption_ScopesAreReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{}
-	selectOption := "all"
+	scopeOption := "global,ula,private,link-local"
 
 	// act
-	_, err := getSelectedIPs(ips, selectOption)
+	scopes, err := getScopes(scopeOption)
 
 	// assert
-	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error even if no IPs are supplied.", ips, selectOption)
-	}
-}
-
-// If no IPs are supplied but a select option (other than "all") is given an error should be returned.
-func Test_getSelectedIPs_NoIPsSupplied_SelectOptionupplied_ErrorIsReturned(t *testing.T) {
-	// arrange
-	selectOptions := []string{
-		"first",
-		"last",
-		"1",
-		"1,2,3",
+	expectedResult := []myip.Scope{myip.ScopeGlobal, myip.ScopeUniqueLocal, myip.ScopePrivate, myip.ScopeLinkLocal}
+	if fmt.Sprintf("%s", scopes) != fmt.Sprintf("%s", expectedResult) {
+		t.Errorf("getScopes(%q) returned %s but should have returned %s", scopeOption, scopes, expectedResult)
 	}
-	for _, selectOption := range selectOptions {
-		ips := []net.IP{}
 
-		// act
-		_, err := getSelectedIPs(ips, selectOption)
-
-		// assert
-		if err == nil {
-			t.Fail()
-			t.Errorf("getSelectedIPs(%q, %q) should return an error.", ips, selectOption)
-		}
+	if err != nil {
+		t.Errorf("getScopes(%q) should not return an error but returned: %s", scopeOption, err.Error())
 	}
 }
 
-// Invalid select options should result in an error.
-func Test_getSelectedIPs_SelectOptionIsInvalid_ErrorIsReturned(t *testing.T) {
+// getScopes should return an error for unknown scope names.
+func Test_getScopes_InvalidOption_ErrorIsReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{}
 	invalidOptions := []string{
-		"  ",
-		" all",
-		"all ",
-		"dasdsadsa",
-		"1 2 3",
-		"1;2;3",
-		"1,",
-		",1,2,3",
+		"public",
+		"global,",
+		"global;ula",
 	}
 
-	for _, selectOption := range invalidOptions {
+	for _, scopeOption := range invalidOptions {
 
 		// act
-		_, err := getSelectedIPs(ips, selectOption)
+		_, err := getScopes(scopeOption)
 
 		// assert
 		if err == nil {
-			t.Fail()
-			t.Errorf("getSelectedIPs(%q, %q) should return an error because the given option is invalid.", ips, selectOption)
+			t.Errorf("getScopes(%q) should return an error because the given option is invalid.", scopeOption)
 		}
 	}
 }
 
-// If the select option "all" is used all IPs should be returned.
-func Test_getSelectedIPs_IPsSupplied_SelectOptionAll_AllIPsAreReturned(t *testing.T) {
+// getMyIPAddrs should keep the zones of the selected IPv6 addresses.
+func Test_getMyIPAddrs_IPProviderHasZonedIPv6Addresses_ZonesAreReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
+	ipProvider := testIPAddrProvider{
+		ipv6IPs: []net.IPAddr{
+			{IP: net.ParseIP("2001:db8::1")},
+			{IP: net.ParseIP("fe80::1"), Zone: "eth0"},
+			{IP: net.ParseIP("fe80::1"), Zone: "wg0"},
+		},
 	}
-	selectOption := "all"
+	selectionOption := "3,2"
+	useIPv4 := false
 
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	ips, err := getMyIPAddrs(ipProvider, selectionOption, useIPv4)
 
 	// assert
-	if fmt.Sprintf("%s", ips) != fmt.Sprintf("%s", selectedIPs) {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should have returned %q.", ips, selectOption, selectedIPs, ips)
+	if len(ips) != 2 || ips[0].String() != "fe80::1%wg0" || ips[1].String() != "fe80::1%eth0" {
+		t.Errorf("getMyIPAddrs(ipProvider, %q, %v) returned %v but should have returned [fe80::1%%wg0 fe80::1%%eth0]", selectionOption, useIPv4, ips)
 	}
 
 	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error but returned: %s.", ips, selectOption, err.Error())
+		t.Errorf("getMyIPAddrs(ipProvider, %q, %v) should not return an error but returned: %s", selectionOption, useIPv4, err.Error())
 	}
 }
 
-// If the select option "first" is used only the first IP should be returned.
-func Test_getSelectedIPs_IPsSupplied_SelectOptionFirst_FirstIPIsReturned(t *testing.T) {
+// getMyIPAddrs should return an error if the IP provider has no IPv4 addresses.
+func Test_getMyIPAddrs_UseIPv4True_IPProviderHasNoIPv4Addresses_ErrorIsReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
+	ipProvider := testIPAddrProvider{
+		ipv6IPs: []net.IPAddr{
+			{IP: net.ParseIP("fe80::1"), Zone: "eth0"},
+		},
 	}
-	selectOption := "first"
+	selectionOption := "all"
+	useIPv4 := true
 
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	ips, err := getMyIPAddrs(ipProvider, selectionOption, useIPv4)
 
 	// assert
-	if len(selectedIPs) != 1 || fmt.Sprintf("%s", selectedIPs[0]) != "127.0.0.1" {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should have returned %q.", ips, selectOption, selectedIPs, ips[:1])
+	if len(ips) > 0 {
+		t.Errorf("getMyIPAddrs(ipProvider, %q, %v) returned %v but should not have returned anything because the IP provider has no IPv4 addresses", selectionOption, useIPv4, ips)
 	}
 
-	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error but returned: %s.", ips, selectOption, err.Error())
+	if err == nil {
+		t.Errorf("getMyIPAddrs(ipProvider, %q, %v) should return an error if the IP provider has no IPv4 addresses", selectionOption, useIPv4)
 	}
 }
 
-// If the select option "last" is used only the last IP should be returned.
-func Test_getSelectedIPs_IPsSupplied_SelectOptionLast_LastIPIsReturned(t *testing.T) {
+// myPrimaryIP should return an error if the destination is not a valid IP address.
+func Test_myPrimaryIP_InvalidDestination_ErrorIsReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
-	}
-	selectOption := "last"
+	destination := "example.com"
 
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	ips, err := myPrimaryIP("all", true, destination, myip.NetworkFilter{})
 
 	// assert
-	if len(selectedIPs) != 1 || fmt.Sprintf("%s", selectedIPs[0]) != "127.0.0.3" {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should have returned %q.", ips, selectOption, selectedIPs, ips[2:3])
-	}
-
-	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error but returned: %s.", ips, selectOption, err.Error())
+	if len(ips) > 0 || err == nil {
+		t.Errorf("myPrimaryIP(%q, %v, %q) returned %q, %v but should have returned an error", "all", true, destination, ips, err)
 	}
 }
 
-// If the select option "1,2,3" is used the IPs should be returned in the specified order.
-func Test_getSelectedIPs_IPsSupplied_SelectOption123_IPsAreReturnedInCorrectOrder(t *testing.T) {
+// myPrimaryIP should return the loopback address if the destination is a loopback address.
+func Test_myPrimaryIP_LoopbackDestination_LoopbackIPIsReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
-	}
-	selectOption := "1,2,3"
+	destination := "127.0.0.1"
 
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	ips, err := myPrimaryIP("all", true, destination, myip.NetworkFilter{})
 
 	// assert
-	expectedResult := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
+	if err != nil {
+		t.Fatalf("myPrimaryIP(%q, %v, %q) should not return an error but returned: %s", "all", true, destination, err.Error())
 	}
 
-	if fmt.Sprintf("%s", expectedResult) != fmt.Sprintf("%s", selectedIPs) {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should have returned %q.", ips, selectOption, selectedIPs, expectedResult)
-	}
-
-	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error but returned: %s.", ips, selectOption, err.Error())
+	if len(ips) != 1 || !ips[0].IsLoopback() {
+		t.Errorf("myPrimaryIP(%q, %v, %q) returned %q but should have returned a loopback address", "all", true, destination, ips)
 	}
 }
 
-// If the select option "3,2,1" is used the IPs should be returned in the specified order.
-func Test_getSelectedIPs_IPsSupplied_SelectOption321_IPsAreReturnedInCorrectOrder(t *testing.T) {
+// getLocalIPOptions should return an error if the sort option is invalid.
+func Test_getLocalIPOptions_InvalidSortOption_ErrorIsReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
-	}
-	selectOption := "3,2,1"
+	orderOption := "alphabetical"
 
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	_, err := getLocalIPOptions("", orderOption, "", "")
 
 	// assert
-	expectedResult := []net.IP{
-		net.ParseIP("127.0.0.3"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.1"),
+	if err == nil {
+		t.Errorf("getLocalIPOptions(%q, %q, %q) should return an error because the sort option is invalid", "", orderOption, "")
 	}
+}
 
-	if fmt.Sprintf("%s", expectedResult) != fmt.Sprintf("%s", selectedIPs) {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should have returned %q.", ips, selectOption, selectedIPs, expectedResult)
-	}
+// getLocalIPOptions should return the parsed order and destination.
+func Test_getLocalIPOptions_ValidOptions_OrderAndDestinationAreReturned(t *testing.T) {
+	// arrange
+	orderOption := "rfc6724"
+	destinationOption := "2001:db8::1"
 
+	// act
+	options, err := getLocalIPOptions("", orderOption, destinationOption, "")
+
+	// assert
 	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error but returned: %s.", ips, selectOption, err.Error())
+		t.Fatalf("getLocalIPOptions(%q, %q, %q) should not return an error but returned: %s", "", orderOption, destinationOption, err.Error())
+	}
+
+	if options.Order != myip.OrderRFC6724 || !options.Destination.Equal(net.ParseIP(destinationOption)) {
+		t.Errorf("getLocalIPOptions(%q, %q, %q) returned %v but should have returned the order %s and the destination %s", "", orderOption, destinationOption, options, myip.OrderRFC6724, destinationOption)
 	}
 }
 
-// If the select option "3,1" is used the IPs should be returned in the specified order.
-func Test_getSelectedIPs_IPsSupplied_SelectOption31_IPsAreReturnedInCorrectOrder(t *testing.T) {
+// getLocalIPOptions should return the parsed address flags.
+func Test_getLocalIPOptions_ExcludeFlagsOption_FlagsAreReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
-	}
-	selectOption := "3,1"
+	excludeFlagsOption := "temporary,deprecated"
 
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	options, err := getLocalIPOptions("", "none", "", excludeFlagsOption)
 
 	// assert
-	expectedResult := []net.IP{
-		net.ParseIP("127.0.0.3"),
-		net.ParseIP("127.0.0.1"),
-	}
-
-	if fmt.Sprintf("%s", expectedResult) != fmt.Sprintf("%s", selectedIPs) {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should have returned %q.", ips, selectOption, selectedIPs, expectedResult)
+	if err != nil {
+		t.Fatalf("getLocalIPOptions(%q, %q, %q, %q) should not return an error but returned: %s", "", "none", "", excludeFlagsOption, err.Error())
 	}
 
-	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error but returned: %s.", ips, selectOption, err.Error())
+	expectedResult := myip.AddressFlagTemporary | myip.AddressFlagDeprecated
+	if options.ExcludeFlags != expectedResult {
+		t.Errorf("getLocalIPOptions(%q, %q, %q, %q) returned the flags %q but should have returned %q", "", "none", "", excludeFlagsOption, options.ExcludeFlags, expectedResult)
 	}
 }
 
-// If the select option "1,1,1,1" is used the IPs should be returned in the specified order.
-// Returning the same IP multiple times should be possible (even though I don't know why you would want that).
-func Test_getSelectedIPs_IPsSupplied_SelectOption1111_IPsAreReturnedInCorrectOrder(t *testing.T) {
+// getLocalIPOptions should return an error if an address flag is unknown.
+func Test_getLocalIPOptions_InvalidExcludeFlagsOption_ErrorIsReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
-	}
-	selectOption := "1,1,1,1"
+	excludeFlagsOption := "temporary,old"
 
 	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	_, err := getLocalIPOptions("", "none", "", excludeFlagsOption)
 
 	// assert
-	expectedResult := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.1"),
+	if err == nil {
+		t.Errorf("getLocalIPOptions(%q, %q, %q, %q) should return an error because the address flag is unknown", "", "none", "", excludeFlagsOption)
 	}
+}
 
-	if fmt.Sprintf("%s", expectedResult) != fmt.Sprintf("%s", selectedIPs) {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should have returned %q.", ips, selectOption, selectedIPs, expectedResult)
-	}
+// getRemoteIPOptions should parse the methods in the given order and the strategy.
+func Test_getRemoteIPOptions_ValidOptions_OptionsAreReturned(t *testing.T) {
+	// act
+	options, err := getRemoteIPOptions("dns,http", "fallback", "", "", "", "", "", 0, time.Second)
 
+	// assert
 	if err != nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should not return an error but returned: %s.", ips, selectOption, err.Error())
+		t.Fatalf("getRemoteIPOptions returned an error: %s", err.Error())
+	}
+
+	if fmt.Sprintf("%s", options.Methods) != "[dns http]" || options.Strategy != myip.StrategyFallback {
+		t.Errorf("getRemoteIPOptions returned %s and %s but should have returned [dns http] and fallback", options.Methods, options.Strategy)
 	}
 }
 
-// If the select option ",1" is used no IPs should be returned but an error.
-func Test_getSelectedIPs_IPsSupplied_SelectOptionIsInvalid_NoIPsAreReturned_ErrorIsReturned(t *testing.T) {
+// getRemoteIPOptions should return an error for unknown methods and strategies.
+func Test_getRemoteIPOptions_InvalidOptions_ErrorIsReturned(t *testing.T) {
 	// arrange
-	ips := []net.IP{
-		net.ParseIP("127.0.0.1"),
-		net.ParseIP("127.0.0.2"),
-		net.ParseIP("127.0.0.3"),
+	inputs := [][]string{
+		{"dns,ftp", "race", "", "", ""},
+		{"http", "majority", "", "", ""},
+		{"http", "race", "ftp://proxy:21", "", ""},
+		{"http", "race", "socks5://", "", ""},
+		{"http", "race", "", "ftp://proxy:21", ""},
+		{"dns", "race", "http://proxy:3128", "", ""},
+		{"http,dns", "race", "", "socks5://127.0.0.1:9050", ""},
+		{"http", "race", "", "", "10.8.0.256"},
 	}
-	selectOption := ",1"
 
-	// act
-	selectedIPs, err := getSelectedIPs(ips, selectOption)
+	for _, input := range inputs {
 
-	// assert
-	if len(selectedIPs) > 0 {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) returned %q but should not have returned any IPs because the select option is invalid.", ips, selectOption, selectedIPs)
+		// act
+		_, err := getRemoteIPOptions(input[0], input[1], input[2], input[3], "", "", input[4], 0, time.Second)
+
+		// assert
+		if err == nil {
+			t.Errorf("getRemoteIPOptions(%q) should return an error", input)
+		}
 	}
+}
 
-	if err == nil {
-		t.Fail()
-		t.Errorf("getSelectedIPs(%q, %q) should return an error but did not.", ips, selectOption)
+// getRemoteIPOptions should return an error if the number of retries
+// is out of range or if the retry backoff is not positive.
+func Test_getRemoteIPOptions_InvalidRetries_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		retries      int
+		retryBackoff time.Duration
+	}{
+		{-1, time.Second},
+		{myip.MaxRetries + 1, time.Second},
+		{math.MaxInt32, time.Second},
+		{3, 0},
+		{3, -time.Second},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		_, err := getRemoteIPOptions("http", "race", "", "", "", "", "", input.retries, input.retryBackoff)
+
+		// assert
+		if err == nil {
+			t.Errorf("getRemoteIPOptions(retries: %d, backoff: %s) should return an error", input.retries, input.retryBackoff)
+		}
 	}
 }
@@ -0,0 +1,115 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"io"
+	"net"
+	"sync"
+)
+
+// defaultUplinkScopes contains the scopes of the local addresses
+// that are checked by the -per-interface mode if no scopes are given.
+var defaultUplinkScopes = []myip.Scope{myip.ScopeGlobal, myip.ScopePrivate, myip.ScopeUniqueLocal, myip.ScopeCGNAT}
+
+// uplinkIP contains the public address that is returned for a local address of a network interface.
+type uplinkIP struct {
+	interfaceName string
+	localIP       net.IP
+	remoteIPs     []net.IP
+	remoteIPError error
+}
+
+// newRemoteIPAddresser creates the remote IP provider that asks
+// through the given interface from the given local address.
+type newRemoteIPAddresser func(interfaceName string, localIP net.IP) ipAddresser
+
+// myUplinkIPs determines the public IPv6 (or IPv4) address of each local address with
+// one of the given scopes (default: global, private, ula, cgnat) that passes the given
+// network filter concurrently. The remote services are asked through the interface
+// of the local address from the local address according to the given options.
+func myUplinkIPs(useIPv4 bool, scopes []myip.Scope, filter myip.NetworkFilter, options myip.RemoteIPProviderOptions) ([]uplinkIP, error) {
+
+	interfaces, interfacesError := myip.GetLocalInterfaceIPs()
+	if interfacesError != nil {
+		return nil, interfacesError
+	}
+
+	newRemoteIPProvider := func(interfaceName string, localIP net.IP) ipAddresser {
+		uplinkOptions := options
+		uplinkOptions.SourceInterface, uplinkOptions.SourceAddress = interfaceName, localIP
+		return myip.NewRemoteIPProviderWithOptions(uplinkOptions)
+	}
+
+	uplinks := getUplinkIPs(interfaces, useIPv4, scopes, filter, newRemoteIPProvider)
+	if len(uplinks) == 0 {
+		return nil, fmt.Errorf("No local %s IPs available.", getIPType(useIPv4))
+	}
+
+	return uplinks, nil
+}
+
+// getUplinkIPs determines the public IPv6 (or IPv4) address of each local address of the given
+// interfaces that has one of the given scopes and passes the given network filter
+// with the remote IP providers created by the given function.
+func getUplinkIPs(interfaces []myip.InterfaceIPs, useIPv4 bool, scopes []myip.Scope, filter myip.NetworkFilter, newRemoteIPProvider newRemoteIPAddresser) []uplinkIP {
+
+	if len(scopes) == 0 {
+		scopes = defaultUplinkScopes
+	}
+
+	localIPOptions := myip.LocalIPProviderOptions{Scopes: scopes, Networks: filter.Networks, ExcludedNetworks: filter.ExcludedNetworks}
+
+	var uplinks []uplinkIP
+	for _, networkInterface := range interfaces {
+		for _, ip := range networkInterface.IPs {
+			if (ip.To4() != nil) != useIPv4 || !localIPOptions.Includes(ip, 0) {
+				continue
+			}
+
+			uplinks = append(uplinks, uplinkIP{interfaceName: networkInterface.Name, localIP: ip})
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(uplinks))
+
+	for index := range uplinks {
+		go func(uplink *uplinkIP) {
+			defer wg.Done()
+			ipProvider := newRemoteIPProvider(uplink.interfaceName, uplink.localIP)
+			if useIPv4 {
+				uplink.remoteIPs, uplink.remoteIPError = ipProvider.GetIPv4Addresses()
+			} else {
+				uplink.remoteIPs, uplink.remoteIPError = ipProvider.GetIPv6Addresses()
+			}
+		}(&uplinks[index])
+	}
+
+	wg.Wait()
+
+	return uplinks
+}
+
+// hasUplinkIP returns true if the public address of at least one of the given uplinks is known.
+func hasUplinkIP(uplinks []uplinkIP) bool {
+	for _, uplink := range uplinks {
+		if uplink.remoteIPError == nil && len(uplink.remoteIPs) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printUplinkIPs writes a table of the interfaces, local addresses and public addresses to the given writer.
+func printUplinkIPs(w io.Writer, uplinks []uplinkIP) {
+	fmt.Fprintf(w, "%-12s %-40s %s\n", "Interface", "Local", "Public")
+	for _, uplink := range uplinks {
+		fmt.Fprintf(w, "%-12s %-40s %s\n", uplink.interfaceName, uplink.localIP, formatInfoIPs(uplink.remoteIPs, uplink.remoteIPError))
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"github.com/andreaskoch/myip/myiptest"
+	"net"
+	"strings"
+	"testing"
+)
+
+// newUplinkTestInterfaces returns two ISP uplinks, an LTE uplink, a link-local and a documentation address.
+func newUplinkTestInterfaces() []myip.InterfaceIPs {
+	return []myip.InterfaceIPs{
+		{Name: "eth0", IPs: myiptest.IPs("192.168.1.2", "fe80::1", "2001:db8::2")},
+		{Name: "eth1", IPs: myiptest.IPs("10.0.0.2")},
+		{Name: "wwan0", IPs: myiptest.IPs("100.64.1.2")},
+	}
+}
+
+// The remote address of each local IPv4 address with the default scopes
+// should be requested through its interface from the local address.
+func Test_getUplinkIPs_DefaultScopes_RemoteIPPerLocalAddress(t *testing.T) {
+	// arrange
+	remoteIPs := map[string]myiptest.Result{
+		"eth0 192.168.1.2": {IPs: myiptest.IPs("203.0.113.5")},
+		"eth1 10.0.0.2":    {IPs: myiptest.IPs("198.51.100.7")},
+		"wwan0 100.64.1.2": {Err: fmt.Errorf("Timeout")},
+	}
+	newRemoteIPProvider := func(interfaceName string, localIP net.IP) ipAddresser {
+		return myiptest.NewIPAddresser(remoteIPs[interfaceName+" "+localIP.String()], myiptest.Result{})
+	}
+
+	// act
+	uplinks := getUplinkIPs(newUplinkTestInterfaces(), true, nil, myip.NetworkFilter{}, newRemoteIPProvider)
+
+	// assert
+	var buffer bytes.Buffer
+	printUplinkIPs(&buffer, uplinks)
+	expectedLines := []string{
+		"eth0         192.168.1.2                              203.0.113.5",
+		"eth1         10.0.0.2                                 198.51.100.7",
+		"wwan0        100.64.1.2                               unavailable (Timeout)",
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != len(expectedLines)+1 {
+		t.Fatalf("printUplinkIPs printed %q but should have printed a header and %d lines", buffer.String(), len(expectedLines))
+	}
+
+	for index, expectedLine := range expectedLines {
+		if lines[index+1] != expectedLine {
+			t.Errorf("printUplinkIPs printed %q but should have printed %q", lines[index+1], expectedLine)
+		}
+	}
+
+	if !hasUplinkIP(uplinks) {
+		t.Errorf("hasUplinkIP returned false but should have returned true")
+	}
+}
+
+// Only the IPv6 addresses with the given scopes should be checked.
+func Test_getUplinkIPs_IPv6Scopes_OnlyMatchingAddressesAreChecked(t *testing.T) {
+	// arrange
+	newRemoteIPProvider := func(interfaceName string, localIP net.IP) ipAddresser {
+		return myiptest.NewIPAddresser(myiptest.Result{}, myiptest.Result{IPs: []net.IP{localIP}})
+	}
+
+	// act
+	uplinks := getUplinkIPs(newUplinkTestInterfaces(), false, []myip.Scope{myip.ScopeDocumentation}, myip.NetworkFilter{}, newRemoteIPProvider)
+
+	// assert
+	if len(uplinks) != 1 || uplinks[0].interfaceName != "eth0" || !uplinks[0].localIP.Equal(net.ParseIP("2001:db8::2")) {
+		t.Errorf("getUplinkIPs returned %+v but should have returned eth0 2001:db8::2", uplinks)
+	}
+}
+
+// Only the local addresses that pass the network filter (-in, -not-in) should be checked.
+func Test_getUplinkIPs_NetworkFilter_OnlyFilteredAddressesAreChecked(t *testing.T) {
+	// arrange
+	newRemoteIPProvider := func(interfaceName string, localIP net.IP) ipAddresser {
+		return myiptest.NewIPAddresser(myiptest.Result{IPs: []net.IP{localIP}}, myiptest.Result{})
+	}
+
+	filter, filterError := myip.ParseNetworkFilter("10.0.0.0/8,100.64.0.0/10", "100.64.0.0/16")
+	if filterError != nil {
+		t.Fatalf("ParseNetworkFilter returned an error: %s", filterError.Error())
+	}
+
+	// act
+	uplinks := getUplinkIPs(newUplinkTestInterfaces(), true, nil, filter, newRemoteIPProvider)
+
+	// assert
+	if len(uplinks) != 1 || uplinks[0].interfaceName != "eth1" || !uplinks[0].localIP.Equal(net.ParseIP("10.0.0.2")) {
+		t.Errorf("getUplinkIPs returned %+v but should have returned eth1 10.0.0.2", uplinks)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"io"
+	"os"
+)
+
+// newMyLocalIPProvider creates a local IP provider with the given options.
+// If a snapshot file is given, the addresses are read from the snapshot
+// instead of the local network interfaces.
+func newMyLocalIPProvider(options myip.LocalIPProviderOptions, snapshotPath string) (myip.LocalIPProvider, error) {
+
+	if snapshotPath == "" {
+		return myip.NewLocalIPProviderWithOptions(options)
+	}
+
+	snapshot, snapshotError := readSnapshotFile(snapshotPath)
+	if snapshotError != nil {
+		return myip.LocalIPProvider{}, snapshotError
+	}
+
+	return myip.NewLocalIPProviderWithSource(snapshot, options), nil
+}
+
+// readSnapshotFile reads the snapshot with the given path.
+func readSnapshotFile(snapshotPath string) (myip.Snapshot, error) {
+
+	file, fileError := os.Open(snapshotPath)
+	if fileError != nil {
+		return myip.Snapshot{}, fmt.Errorf("Unable to open the snapshot: %s", fileError.Error())
+	}
+
+	defer file.Close()
+
+	return myip.ReadSnapshot(file)
+}
+
+// writeSnapshot writes a snapshot of the local network interfaces to the given writer.
+func writeSnapshot(writer io.Writer) error {
+
+	snapshot, snapshotError := myip.TakeSnapshot()
+	if snapshotError != nil {
+		return snapshotError
+	}
+
+	return snapshot.Write(writer)
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"io"
+	"net"
+)
+
+// The ipNetworkAddresser interface provides functions for
+// retrieving IPv4 and IPv6 addresses including their network masks.
+type ipNetworkAddresser interface {
+	GetIPv4Networks() ([]net.IPNet, error)
+	GetIPv6Networks() ([]net.IPNet, error)
+}
+
+// myLocalSubnets returns the subnets of the current local IPv6 (or IPv4) addresses.
+// The local addresses are filtered according to the given options.
+// If a snapshot file is given, the addresses are read from the snapshot.
+func myLocalSubnets(selectionOption string, useIPv4 bool, options myip.LocalIPProviderOptions, snapshotPath string) ([]myip.Subnet, error) {
+
+	ipProvider, ipProviderError := newMyLocalIPProvider(options, snapshotPath)
+	if ipProviderError != nil {
+		return nil, fmt.Errorf("%s\n", ipProviderError.Error())
+	}
+
+	return getMySubnets(ipProvider, selectionOption, useIPv4)
+}
+
+// getMySubnets returns the subnets of the selected IPv6 or IPv4 addresses from the given IP provider.
+func getMySubnets(ipProvider ipNetworkAddresser, selectionOption string, useIPv4 bool) ([]myip.Subnet, error) {
+
+	// IPv6 vs IPv4
+	var allNetworks []net.IPNet
+	var networkErr error
+	if useIPv4 {
+		allNetworks, networkErr = ipProvider.GetIPv4Networks()
+	} else {
+		allNetworks, networkErr = ipProvider.GetIPv6Networks()
+	}
+
+	// handle errors
+	if networkErr != nil {
+		return nil, fmt.Errorf("%s\n", networkErr.Error())
+	}
+
+	// abort if no IPs are returned
+	if len(allNetworks) == 0 {
+		return []myip.Subnet{}, fmt.Errorf("No %s IPs available.", getIPType(useIPv4))
+	}
+
+	// select one or more IPs
+	var keys []string
+	for _, network := range allNetworks {
+		keys = append(keys, network.String())
+	}
+
+	selection, selectionError := myip.ParseSelection(selectionOption)
+	if selectionError != nil {
+		return nil, fmt.Errorf("%s\n", selectionError.Error())
+	}
+
+	selectedIndexes, ipSelectionError := selection.Indexes(keys)
+	if ipSelectionError != nil {
+		return nil, fmt.Errorf("%s\n", ipSelectionError.Error())
+	}
+
+	var subnets []myip.Subnet
+	for _, index := range selectedIndexes {
+		subnets = append(subnets, myip.NewSubnet(allNetworks[index]))
+	}
+
+	return subnets, nil
+}
+
+// printSubnets writes the details of the given subnets to the given writer
+// (one block per subnet, separated by an empty line).
+func printSubnets(w io.Writer, subnets []myip.Subnet) {
+	for index, subnet := range subnets {
+		if index > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+
+		fmt.Fprintf(w, "%-11s %s\n", "Address:", subnet.IP)
+		fmt.Fprintf(w, "%-11s %s = %d\n", "Netmask:", net.IP(subnet.Netmask), subnet.PrefixLength)
+		fmt.Fprintf(w, "%-11s %s/%d\n", "Network:", subnet.Network, subnet.PrefixLength)
+		if subnet.Broadcast != nil {
+			fmt.Fprintf(w, "%-11s %s\n", "Broadcast:", subnet.Broadcast)
+		}
+
+		fmt.Fprintf(w, "%-11s %s\n", "HostMin:", subnet.FirstHost)
+		fmt.Fprintf(w, "%-11s %s\n", "HostMax:", subnet.LastHost)
+		fmt.Fprintf(w, "%-11s %s\n", "Hosts:", subnet.HostCount)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// testNetworkProvider returns a fixed list of networks.
+type testNetworkProvider struct {
+	ipv4Networks []net.IPNet
+	ipv6Networks []net.IPNet
+}
+
+func (p testNetworkProvider) GetIPv4Networks() ([]net.IPNet, error) {
+	return p.ipv4Networks, nil
+}
+
+func (p testNetworkProvider) GetIPv6Networks() ([]net.IPNet, error) {
+	return p.ipv6Networks, nil
+}
+
+// getMySubnets and printSubnets should print the details of the selected subnet.
+func Test_getMySubnets_SelectFirst_SubnetOfFirstIPIsPrinted(t *testing.T) {
+	// arrange
+	ipProvider := testNetworkProvider{
+		ipv4Networks: []net.IPNet{mustParseNetwork("192.168.1.10/24"), mustParseNetwork("10.0.0.5/8")},
+	}
+	output := new(bytes.Buffer)
+
+	// act
+	subnets, err := getMySubnets(ipProvider, "first", true)
+	printSubnets(output, subnets)
+
+	// assert
+	if err != nil {
+		t.Fatalf("getMySubnets returned an error: %s", err.Error())
+	}
+
+	expectedOutput := "Address:    192.168.1.10\n" +
+		"Netmask:    255.255.255.0 = 24\n" +
+		"Network:    192.168.1.0/24\n" +
+		"Broadcast:  192.168.1.255\n" +
+		"HostMin:    192.168.1.1\n" +
+		"HostMax:    192.168.1.254\n" +
+		"Hosts:      254\n"
+	if output.String() != expectedOutput {
+		t.Errorf("printSubnets printed %q but should have printed %q", output.String(), expectedOutput)
+	}
+}
+
+// mustParseNetwork parses the given CIDR notation and keeps the host part of the address.
+func mustParseNetwork(cidr string) net.IPNet {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return net.IPNet{IP: ip, Mask: network.Mask}
+}
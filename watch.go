@@ -0,0 +1,66 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/andreaskoch/myip"
+	"io"
+)
+
+// watchLocalIP writes the changes of the local IPv6 (or IPv4) addresses that match
+// the given options to the given writer as they happen ("add <ip>", "remove <ip>").
+// The current addresses are written as "add" lines first.
+func watchLocalIP(w io.Writer, useIPv4 bool, options myip.LocalIPProviderOptions) error {
+
+	watcher, err := myip.NewAddressWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Close()
+
+	visibleAddresses := make(map[string]bool)
+	for {
+		event, err := watcher.Next()
+		if err != nil {
+			return err
+		}
+
+		if line, ok := getWatchLine(event, visibleAddresses, useIPv4, options); ok {
+			fmt.Fprintf(w, "%s\n", line)
+		}
+	}
+}
+
+// getWatchLine returns the output line for the given address event ("add <ip>", "remove <ip>")
+// and updates the given set of visible addresses. Events that do not change the set of
+// addresses matching the given family and options are ignored (the second return value is false).
+func getWatchLine(event myip.AddressEvent, visibleAddresses map[string]bool, useIPv4 bool, options myip.LocalIPProviderOptions) (string, bool) {
+
+	// ignore addresses of the other family
+	if (event.Address.IP.To4() != nil) != useIPv4 {
+		return "", false
+	}
+
+	// the same address can be assigned to multiple interfaces
+	address := event.Address.IPAddr()
+	key := fmt.Sprintf("%d/%s", event.Address.InterfaceIndex, address.String())
+
+	isVisible := event.Type != myip.AddressRemoved && options.Includes(event.Address.IP, event.Address.Flags)
+	wasVisible := visibleAddresses[key]
+
+	switch {
+	case isVisible && !wasVisible:
+		visibleAddresses[key] = true
+		return fmt.Sprintf("%s %s", myip.AddressAdded, address.String()), true
+
+	case !isVisible && wasVisible:
+		delete(visibleAddresses, key)
+		return fmt.Sprintf("%s %s", myip.AddressRemoved, address.String()), true
+	}
+
+	return "", false
+}
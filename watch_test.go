@@ -0,0 +1,100 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/andreaskoch/myip"
+	"net"
+	"testing"
+)
+
+// getWatchLine should report added and removed addresses of the selected family.
+func Test_getWatchLine_AddressAddedAndRemoved_AddAndRemoveLinesAreReturned(t *testing.T) {
+	// arrange
+	visibleAddresses := make(map[string]bool)
+	address := myip.AddressInfo{Interface: "eth0", IP: net.ParseIP("2001:db8::10")}
+	options := myip.LocalIPProviderOptions{}
+
+	// act
+	addLine, addOk := getWatchLine(myip.AddressEvent{Type: myip.AddressAdded, Address: address}, visibleAddresses, false, options)
+	removeLine, removeOk := getWatchLine(myip.AddressEvent{Type: myip.AddressRemoved, Address: address}, visibleAddresses, false, options)
+
+	// assert
+	if !addOk || addLine != "add 2001:db8::10" {
+		t.Errorf("getWatchLine returned %q, %v for the added address but should have returned %q, true", addLine, addOk, "add 2001:db8::10")
+	}
+
+	if !removeOk || removeLine != "remove 2001:db8::10" {
+		t.Errorf("getWatchLine returned %q, %v for the removed address but should have returned %q, true", removeLine, removeOk, "remove 2001:db8::10")
+	}
+}
+
+// getWatchLine should ignore addresses of the other family.
+func Test_getWatchLine_UseIPv4_IPv6AddressAdded_EventIsIgnored(t *testing.T) {
+	// arrange
+	visibleAddresses := make(map[string]bool)
+	address := myip.AddressInfo{Interface: "eth0", IP: net.ParseIP("2001:db8::10")}
+
+	// act
+	line, ok := getWatchLine(myip.AddressEvent{Type: myip.AddressAdded, Address: address}, visibleAddresses, true, myip.LocalIPProviderOptions{})
+
+	// assert
+	if ok {
+		t.Errorf("getWatchLine returned %q for an IPv6 address but should have ignored it because IPv4 is selected", line)
+	}
+}
+
+// getWatchLine should report an address as removed if it becomes deprecated and deprecated addresses are excluded.
+func Test_getWatchLine_AddressBecomesDeprecated_DeprecatedAddressesExcluded_RemoveLineIsReturned(t *testing.T) {
+	// arrange
+	visibleAddresses := map[string]bool{"0/2001:db8::10": true}
+	address := myip.AddressInfo{Interface: "eth0", IP: net.ParseIP("2001:db8::10"), Flags: myip.AddressFlagDeprecated}
+	options := myip.LocalIPProviderOptions{ExcludeFlags: myip.AddressFlagDeprecated}
+
+	// act
+	line, ok := getWatchLine(myip.AddressEvent{Type: myip.AddressUpdated, Address: address}, visibleAddresses, false, options)
+
+	// assert
+	if !ok || line != "remove 2001:db8::10" {
+		t.Errorf("getWatchLine returned %q, %v but should have returned %q, true", line, ok, "remove 2001:db8::10")
+	}
+}
+
+// getWatchLine should print link-local addresses with their zone.
+func Test_getWatchLine_LinkLocalAddressAdded_ZoneIsReturned(t *testing.T) {
+	// arrange
+	visibleAddresses := make(map[string]bool)
+	address := myip.AddressInfo{Interface: "wg0", IP: net.ParseIP("fe80::1")}
+	options := myip.LocalIPProviderOptions{IncludeLinkLocal: true}
+
+	// act
+	line, ok := getWatchLine(myip.AddressEvent{Type: myip.AddressAdded, Address: address}, visibleAddresses, false, options)
+
+	// assert
+	if !ok || line != "add fe80::1%wg0" {
+		t.Errorf("getWatchLine returned %q, %v but should have returned %q, true", line, ok, "add fe80::1%wg0")
+	}
+}
+
+// getWatchLine should track the same address on different interfaces separately.
+func Test_getWatchLine_SameAddressOnTwoInterfaces_RemovalFromOneInterfaceIsTracked(t *testing.T) {
+	// arrange
+	visibleAddresses := make(map[string]bool)
+	eth0Address := myip.AddressInfo{Interface: "eth0", InterfaceIndex: 2, IP: net.ParseIP("10.0.0.1")}
+	eth1Address := myip.AddressInfo{Interface: "eth1", InterfaceIndex: 3, IP: net.ParseIP("10.0.0.1")}
+	options := myip.LocalIPProviderOptions{}
+
+	getWatchLine(myip.AddressEvent{Type: myip.AddressAdded, Address: eth0Address}, visibleAddresses, true, options)
+	getWatchLine(myip.AddressEvent{Type: myip.AddressAdded, Address: eth1Address}, visibleAddresses, true, options)
+	getWatchLine(myip.AddressEvent{Type: myip.AddressRemoved, Address: eth0Address}, visibleAddresses, true, options)
+
+	// act
+	line, ok := getWatchLine(myip.AddressEvent{Type: myip.AddressRemoved, Address: eth1Address}, visibleAddresses, true, options)
+
+	// assert
+	if !ok || line != "remove 10.0.0.1" {
+		t.Errorf("getWatchLine returned %q, %v for the removal from eth1 but should have returned %q, true", line, ok, "remove 10.0.0.1")
+	}
+}